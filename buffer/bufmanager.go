@@ -1,75 +1,207 @@
 package buffer
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 
 	"github.com/tinydb/storage"
 )
 
 const (
-	maxFrames = 1024 // todo: make config-driven
+	maxFrames  = 1024 // todo: make config-driven
+	shardCount = 64   // Page table partitions, must be a power of 2
 )
 
 var (
 	ErrNoFrameAvailable = errors.New("no available frame for page")
+	ErrPageNotPinned    = errors.New("page isn't pinned")
 )
 
+// pageTableShard maps a subset of page ids to the frames holding them.
+type pageTableShard struct {
+	pages map[storage.PageId]*BufferPage
+	mutex *sync.Mutex
+
+	// Counters protected by the shard mutex
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+// Manager caches pages in a fixed set of frames.
+// The page table is partitioned to reduce contention, frames state is handled with atomics,
+// and no lock is held while reading or writing pages to storage.
+// Eviction follows the clock algorithm.
 type Manager struct {
-	store       *storage.Manager
-	directory   *storage.PageDirectory
-	pages       map[storage.PageId]*BufferPage
-	mostRecent  *BufferPage
-	leastRecent *BufferPage
-	mutex       *sync.Mutex
+	store     *storage.Manager
+	directory *storage.PageDirectory
+	frames    []*BufferPage
+	shards    []pageTableShard
+	clockHand *atomic.Uint64
+
+	accessTick  *atomic.Uint64 // Logical clock ordering page accesses
+	dirtyWrites *atomic.Uint64
+	frameWaits  *atomic.Uint64
+
+	// Goroutines waiting for a frame to be unpinned
+	frameWaiters *atomic.Int32
+	frameFreed   chan struct{} // Closed and replaced each time a frame gets fully unpinned while there are waiters
+	waitMutex    *sync.Mutex
+
+	pinTracker *pinTracker // Nil unless pin tracking is enabled
 }
 
-func NewBufferManager(store *storage.Manager, directory *storage.PageDirectory) *Manager {
-	return &Manager{
+type Option func(*Manager)
+
+func NewBufferManager(store *storage.Manager, directory *storage.PageDirectory, options ...Option) *Manager {
+	frames := make([]*BufferPage, maxFrames)
+	for i := range frames {
+		frames[i] = newBufferPage()
+	}
+
+	shards := make([]pageTableShard, shardCount)
+	for i := range shards {
+		shards[i] = pageTableShard{
+			pages: make(map[storage.PageId]*BufferPage, maxFrames/shardCount),
+			mutex: &sync.Mutex{},
+		}
+	}
+
+	manager := &Manager{
 		store:     store,
 		directory: directory,
-		pages:     make(map[storage.PageId]*BufferPage, maxFrames),
-		mutex:     &sync.Mutex{},
+		frames:    frames,
+		shards:    shards,
+		clockHand: &atomic.Uint64{},
+
+		accessTick:  &atomic.Uint64{},
+		dirtyWrites: &atomic.Uint64{},
+		frameWaits:  &atomic.Uint64{},
+
+		frameWaiters: &atomic.Int32{},
+		frameFreed:   make(chan struct{}),
+		waitMutex:    &sync.Mutex{},
+	}
+	for _, option := range options {
+		option(manager)
 	}
+	return manager
 }
 
-func (m *Manager) GetPage(pageId storage.PageId) (*BufferPage, error) {
-	m.mutex.Lock()
-	defer m.mutex.Unlock()
+// GetPage pins the requested page, loading it if it isn't in the pool, and returns the id of the pin.
+// When all frames are pinned, it waits for one to be released until ctx is done.
+func (m *Manager) GetPage(ctx context.Context, pageId storage.PageId) (*BufferPage, PinId, error) {
+	shard := m.getShard(pageId)
+	for {
+		shard.mutex.Lock()
+		if page, found := shard.pages[pageId]; found {
+			page.pin(m.accessTick.Add(1))
+			shard.hits++
+			shard.mutex.Unlock()
 
-	if page, found := m.pages[pageId]; found {
-		page.pinCount++
-		m.setMostRecent(page)
-		return page, nil
-	}
+			if err := page.waitLoaded(ctx); err != nil {
+				m.unpin(page)
+				return nil, 0, err
+			}
+			return page, m.pinTracker.track(page), nil
+		}
+		shard.mutex.Unlock()
 
-	if len(m.pages) == maxFrames {
-		ok, err := m.tryEvict()
+		frame, err := m.acquireFrame(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("page eviction failed: %w", err)
+			return nil, 0, err
 		}
-		if !ok {
-			return nil, ErrNoFrameAvailable
+
+		shard.mutex.Lock()
+		if _, found := shard.pages[pageId]; found {
+			// Loaded by another goroutine in the meantime, give the frame back
+			shard.mutex.Unlock()
+			m.unpin(frame)
+			continue
 		}
+		frame.pageId = pageId
+		frame.valid = true
+		frame.loaded = make(chan struct{})
+		frame.loadErr = nil
+		frame.referenced.Store(true)
+		frame.lastAccess.Store(m.accessTick.Add(1))
+		shard.pages[pageId] = frame
+		shard.misses++
+		shard.mutex.Unlock()
+
+		if err := m.loadPage(ctx, frame); err != nil {
+			return nil, 0, err
+		}
+		return frame, m.pinTracker.track(frame), nil
 	}
+}
 
-	page, err := m.loadPage(pageId)
-	if err != nil {
-		return nil, err
+// ReleasePagePin releases a pin obtained with GetPage, given the pin id GetPage returned.
+// Releasing a page that isn't pinned fails, or panics when pin tracking is enabled, as does releasing a pin twice.
+func (m *Manager) ReleasePagePin(page *BufferPage, pinId PinId) error {
+	m.pinTracker.untrack(page, pinId)
+
+	for {
+		pinCount := page.pinCount.Load()
+		if pinCount <= 0 {
+			return ErrPageNotPinned
+		}
+		if page.pinCount.CompareAndSwap(pinCount, pinCount-1) {
+			if pinCount == 1 {
+				m.signalFrameFreed()
+			}
+			return nil
+		}
 	}
-	page.pinCount++
-	return page, nil
 }
 
-func (m *Manager) ReleasePagePin(page *BufferPage) error {
-	m.mutex.Lock()
-	defer m.mutex.Unlock()
-	page.pinCount--
+func (m *Manager) unpin(page *BufferPage) {
+	if page.pinCount.Add(-1) == 0 {
+		m.signalFrameFreed()
+	}
+}
+
+func (m *Manager) signalFrameFreed() {
+	if m.frameWaiters.Load() == 0 {
+		return
+	}
+
+	m.waitMutex.Lock()
+	close(m.frameFreed)
+	m.frameFreed = make(chan struct{})
+	m.waitMutex.Unlock()
+}
+
+// loadPage reads the page of a frame registered in the page table.
+// Other goroutines requesting the same page wait for it to complete.
+func (m *Manager) loadPage(ctx context.Context, frame *BufferPage) error {
+	page, err := m.readPage(ctx, frame.pageId)
+	if err != nil {
+		shard := m.getShard(frame.pageId)
+		shard.mutex.Lock()
+		delete(shard.pages, frame.pageId)
+		frame.valid = false
+		frame.loadErr = err
+		close(frame.loaded)
+		shard.mutex.Unlock()
+
+		m.unpin(frame)
+		return err
+	}
+
+	frame.Page = page
+	close(frame.loaded)
 	return nil
 }
 
-func (m *Manager) loadPage(pageId storage.PageId) (*BufferPage, error) {
+func (m *Manager) readPage(ctx context.Context, pageId storage.PageId) (*storage.Page, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	loc, err := m.directory.GetPageLoc(pageId)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get page location: %w", err)
@@ -78,68 +210,114 @@ func (m *Manager) loadPage(pageId storage.PageId) (*BufferPage, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to get page from storage: %w", err)
 	}
+	return page, nil
+}
 
-	bufPage := NewBufferPage(page)
-	m.pages[pageId] = bufPage
+// acquireFrame returns an empty frame pinned by the caller.
+// If none can be evicted, it waits for a frame to be unpinned or ctx to be done.
+func (m *Manager) acquireFrame(ctx context.Context) (*BufferPage, error) {
+	for {
+		frame, err := m.sweep()
+		if !errors.Is(err, ErrNoFrameAvailable) {
+			return frame, err
+		}
 
-	if m.mostRecent == nil {
-		m.mostRecent = bufPage
-		m.leastRecent = bufPage
-		return bufPage, nil
-	}
+		// Register as waiter before retrying so that no release happening in between is missed
+		m.frameWaiters.Add(1)
+		m.waitMutex.Lock()
+		freed := m.frameFreed
+		m.waitMutex.Unlock()
 
-	m.mostRecent.moreRecent = bufPage
-	m.mostRecent = bufPage
-	return bufPage, nil
+		frame, err = m.sweep()
+		if !errors.Is(err, ErrNoFrameAvailable) {
+			m.frameWaiters.Add(-1)
+			return frame, err
+		}
+
+		m.frameWaits.Add(1)
+		select {
+		case <-freed:
+			m.frameWaiters.Add(-1)
+		case <-ctx.Done():
+			m.frameWaiters.Add(-1)
+			return nil, fmt.Errorf("%w: %w", ErrNoFrameAvailable, ctx.Err())
+		}
+	}
 }
 
-func (m *Manager) tryEvict() (bool, error) {
-	candidate := m.leastRecent
-	var prev *BufferPage
-	for candidate != nil {
-		if candidate.pinCount != 0 {
-			prev = candidate
-			candidate = candidate.moreRecent
+// sweep runs the clock over the frames to find one to evict.
+// The returned frame is pinned by the caller.
+func (m *Manager) sweep() (*BufferPage, error) {
+	// First sweep may only clear reference bits
+	for range 3 * len(m.frames) {
+		frame := m.frames[m.clockHand.Add(1)%uint64(len(m.frames))]
+		if frame.pinCount.Load() != 0 {
 			continue
 		}
-
-		// Evict
-		candidate.Latch.Lock()
-		defer candidate.Latch.Unlock()
-
-		if candidate.dirty {
-			err := m.store.WritePage(candidate.Page)
-			if err != nil {
-				return false, fmt.Errorf("dirty page write for eviction failed: %w", err)
-			}
+		if frame.referenced.Swap(false) {
+			continue
+		}
+		if !frame.pinCount.CompareAndSwap(0, 1) {
+			continue
 		}
 
-		// It is assumed that prev is only nil if page == m.mostRecent
-		delete(m.pages, candidate.Page.Id)
-		if m.leastRecent == candidate {
-			m.leastRecent = candidate.moreRecent
-		} else if m.mostRecent == candidate {
-			prev.moreRecent = nil
-			m.mostRecent = prev
-		} else {
-			prev.moreRecent = candidate.moreRecent
+		ok, err := m.evict(frame)
+		if err != nil {
+			m.unpin(frame)
+			return nil, fmt.Errorf("page eviction failed: %w", err)
 		}
-		return true, nil
+		if !ok {
+			m.unpin(frame)
+			continue
+		}
+		return frame, nil
 	}
 
-	return false, nil
+	return nil, ErrNoFrameAvailable
 }
 
-func (m *Manager) setMostRecent(page *BufferPage) {
-	prevMostRecent := m.mostRecent
-	if prevMostRecent == page {
-		// Already most recent
-		return
+// evict flushes the content of a frame pinned by the caller and removes it from the page table.
+// It fails if the frame was pinned or modified by another goroutine in the meantime.
+func (m *Manager) evict(frame *BufferPage) (bool, error) {
+	if !frame.valid {
+		return true, nil
 	}
 
-	if m.leastRecent == page {
-		m.leastRecent = page.moreRecent
+	if frame.dirty.Swap(false) {
+		frame.Latch.RLock()
+		err := m.store.WritePage(frame.Page)
+		frame.Latch.RUnlock()
+		if err != nil {
+			frame.dirty.Store(true)
+			return false, fmt.Errorf("dirty page write for eviction failed: %w", err)
+		}
+		m.dirtyWrites.Add(1)
+	}
+
+	shard := m.getShard(frame.pageId)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	if frame.pinCount.Load() != 1 || frame.dirty.Load() {
+		// Used while being written
+		return false, nil
+	}
+
+	delete(shard.pages, frame.pageId)
+	shard.evictions++
+	frame.valid = false
+	frame.Page = nil
+	return true, nil
+}
+
+func (m *Manager) getShard(pageId storage.PageId) *pageTableShard {
+	// FNV-1a
+	hash := uint32(2166136261)
+	for i := 0; i < len(pageId.Relation); i++ {
+		hash ^= uint32(pageId.Relation[i])
+		hash *= 16777619
 	}
-	prevMostRecent.moreRecent = page
-	m.mostRecent = page
+	hash ^= pageId.Id
+	hash *= 16777619
+	return &m.shards[hash&(shardCount-1)]
 }
@@ -0,0 +1,161 @@
+package buffer
+
+import (
+	"context"
+	"encoding/binary"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/tinydb/storage"
+)
+
+const testRelation = "test"
+
+// newTestManager returns a buffer manager over a relation file of pageCount zeroed pages.
+func newTestManager(tb testing.TB, pageCount uint32) *Manager {
+	tb.Helper()
+
+	store := storage.NewStorageManager()
+	directory := storage.NewPageDirectory(tb.TempDir())
+	fpath, err := directory.RegisterFile(testRelation, testRelation)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err := store.CreateFile(fpath); err != nil {
+		tb.Fatal(err)
+	}
+	if _, err := store.ExtendFile(fpath, pageCount); err != nil {
+		tb.Fatal(err)
+	}
+	for id := range pageCount {
+		if _, err := directory.RegisterPage(storage.PageId{Id: id, Relation: testRelation}, id*storage.PageSize); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	return NewBufferManager(store, directory)
+}
+
+func TestConcurrentFetchAcrossShards(t *testing.T) {
+	const (
+		pageCount  = 2 * maxFrames // Forces evictions
+		goroutines = 16
+		fetches    = 2000
+	)
+	manager := newTestManager(t, pageCount)
+	ctx := context.Background()
+
+	// Each page counts the writes it received in its first bytes
+	writes := make([]uint32, pageCount)
+	var writesMutex sync.Mutex
+	var wg sync.WaitGroup
+	errs := make(chan error, goroutines)
+	for g := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			random := rand.New(rand.NewSource(int64(g)))
+			for range fetches {
+				pageId := storage.PageId{Id: uint32(random.Intn(pageCount)), Relation: testRelation}
+				if random.Intn(4) == 0 {
+					guard, err := manager.FetchWrite(ctx, pageId)
+					if err != nil {
+						errs <- err
+						return
+					}
+					page := guard.Page()
+					binary.BigEndian.PutUint32(page.Data, binary.BigEndian.Uint32(page.Data)+1)
+					writesMutex.Lock()
+					writes[pageId.Id]++
+					writesMutex.Unlock()
+					if err := guard.Release(); err != nil {
+						errs <- err
+						return
+					}
+					continue
+				}
+
+				guard, err := manager.FetchRead(ctx, pageId)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if guard.Page().Id != pageId {
+					t.Errorf("fetched page %s, got %s", pageId, guard.Page().Id)
+				}
+				if err := guard.Release(); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	stats := manager.Stats()
+	if stats.Hits+stats.Misses != goroutines*fetches {
+		t.Errorf("expected %d page requests, got %d hits and %d misses", goroutines*fetches, stats.Hits, stats.Misses)
+	}
+	if stats.PinnedPages != 0 {
+		t.Errorf("expected no pinned page, got %d", stats.PinnedPages)
+	}
+	if stats.UsedFrames > maxFrames {
+		t.Errorf("expected at most %d used frames, got %d", maxFrames, stats.UsedFrames)
+	}
+	for i := range manager.shards {
+		for pageId, frame := range manager.shards[i].pages {
+			if frame.pageId != pageId || manager.getShard(pageId) != &manager.shards[i] {
+				t.Errorf("page %s is mapped in the wrong shard or frame", pageId)
+			}
+		}
+	}
+
+	// Dirty pages written on eviction or still buffered hold every write
+	for id := range uint32(pageCount) {
+		guard, err := manager.FetchRead(ctx, storage.PageId{Id: id, Relation: testRelation})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count := binary.BigEndian.Uint32(guard.Page().Data); count != writes[id] {
+			t.Errorf("page %d: expected %d writes, got %d", id, writes[id], count)
+		}
+		if err := guard.Release(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFetchReadHotSet(b *testing.B) {
+	const hotPages = 64
+	manager := newTestManager(b, hotPages)
+	ctx := context.Background()
+	for id := range uint32(hotPages) {
+		guard, err := manager.FetchRead(ctx, storage.PageId{Id: id, Relation: testRelation})
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := guard.Release(); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		random := rand.New(rand.NewSource(rand.Int63()))
+		for pb.Next() {
+			guard, err := manager.FetchRead(ctx, storage.PageId{Id: uint32(random.Intn(hotPages)), Relation: testRelation})
+			if err != nil {
+				b.Error(err)
+				return
+			}
+			if err := guard.Release(); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
@@ -1,28 +1,52 @@
 package buffer
 
 import (
+	"context"
 	"sync"
+	"sync/atomic"
 
 	"github.com/tinydb/storage"
 )
 
+// BufferPage is a frame of the buffer pool.
+// Its identity (pageId, valid, Page) only changes while the frame is exclusively pinned
+// by the goroutine evicting or loading it, and is published through the page table shard lock.
 type BufferPage struct {
 	Page  *storage.Page
 	Latch *sync.RWMutex
 
-	dirty      bool
-	pinCount   int
-	moreRecent *BufferPage
+	pageId     storage.PageId
+	valid      bool
+	loaded     chan struct{} // Closed once Page is loaded or loadErr is set
+	loadErr    error
+	dirty      atomic.Bool
+	pinCount   atomic.Int32
+	referenced atomic.Bool   // Clock replacement reference bit
+	lastAccess atomic.Uint64 // Manager access tick of the last pin
 }
 
-func NewBufferPage(page *storage.Page) *BufferPage {
+func newBufferPage() *BufferPage {
 	return &BufferPage{
-		Page:  page,
 		Latch: &sync.RWMutex{},
-		dirty: false,
 	}
 }
 
 func (p *BufferPage) SetDirty() {
-	p.dirty = true
+	p.dirty.Store(true)
+}
+
+func (p *BufferPage) pin(tick uint64) {
+	p.pinCount.Add(1)
+	p.referenced.Store(true)
+	p.lastAccess.Store(tick)
+}
+
+// waitLoaded blocks until the page loading performed by another goroutine is done.
+func (p *BufferPage) waitLoaded(ctx context.Context) error {
+	select {
+	case <-p.loaded:
+		return p.loadErr
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
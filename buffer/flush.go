@@ -0,0 +1,66 @@
+package buffer
+
+import (
+	"fmt"
+
+	"github.com/tinydb/storage"
+)
+
+// FlushAll writes every dirty page of the pool to storage, keeping them buffered.
+func (m *Manager) FlushAll() error {
+	return m.flush(func(storage.PageId) bool {
+		return true
+	})
+}
+
+// FlushRelation writes every dirty page of a relation to storage, keeping them buffered.
+func (m *Manager) FlushRelation(relation string) error {
+	return m.flush(func(pageId storage.PageId) bool {
+		return pageId.Relation == relation
+	})
+}
+
+func (m *Manager) flush(filter func(storage.PageId) bool) error {
+	for i := range m.shards {
+		shard := &m.shards[i]
+
+		// Pin dirty pages so that they stay in their frame once the shard is unlocked
+		shard.mutex.Lock()
+		var dirtyPages []*BufferPage
+		for pageId, page := range shard.pages {
+			if filter(pageId) && page.dirty.Load() {
+				page.pinCount.Add(1)
+				dirtyPages = append(dirtyPages, page)
+			}
+		}
+		shard.mutex.Unlock()
+
+		var flushErr error
+		for _, page := range dirtyPages {
+			if flushErr == nil {
+				flushErr = m.flushPage(page)
+			}
+			m.unpin(page)
+		}
+		if flushErr != nil {
+			return flushErr
+		}
+	}
+	return nil
+}
+
+func (m *Manager) flushPage(page *BufferPage) error {
+	if !page.dirty.Swap(false) {
+		return nil
+	}
+
+	page.Latch.RLock()
+	err := m.store.WritePage(page.Page)
+	page.Latch.RUnlock()
+	if err != nil {
+		page.dirty.Store(true)
+		return fmt.Errorf("dirty page write failed: %w", err)
+	}
+	m.dirtyWrites.Add(1)
+	return nil
+}
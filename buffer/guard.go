@@ -0,0 +1,86 @@
+package buffer
+
+import (
+	"context"
+
+	"github.com/tinydb/storage"
+)
+
+// ReadGuard holds a pin and a shared latch on a buffered page.
+// It must be released exactly once, usually with a defer right after the fetch.
+type ReadGuard struct {
+	manager *Manager
+	page    *BufferPage
+	pinId   PinId
+}
+
+// WriteGuard holds a pin and an exclusive latch on a buffered page.
+// The page is marked dirty on release.
+type WriteGuard struct {
+	manager *Manager
+	page    *BufferPage
+	pinId   PinId
+}
+
+// FetchRead pins the page and acquires its latch in shared mode.
+func (m *Manager) FetchRead(ctx context.Context, pageId storage.PageId) (*ReadGuard, error) {
+	page, pinId, err := m.GetPage(ctx, pageId)
+	if err != nil {
+		return nil, err
+	}
+
+	page.Latch.RLock()
+	return &ReadGuard{
+		manager: m,
+		page:    page,
+		pinId:   pinId,
+	}, nil
+}
+
+// FetchWrite pins the page and acquires its latch in exclusive mode.
+func (m *Manager) FetchWrite(ctx context.Context, pageId storage.PageId) (*WriteGuard, error) {
+	page, pinId, err := m.GetPage(ctx, pageId)
+	if err != nil {
+		return nil, err
+	}
+
+	page.Latch.Lock()
+	return &WriteGuard{
+		manager: m,
+		page:    page,
+		pinId:   pinId,
+	}, nil
+}
+
+func (g *ReadGuard) Page() *storage.Page {
+	return g.page.Page
+}
+
+// Release unlatches and unpins the page. Calling it more than once is a no-op.
+func (g *ReadGuard) Release() error {
+	if g.page == nil {
+		return nil
+	}
+
+	page := g.page
+	g.page = nil
+	page.Latch.RUnlock()
+	return g.manager.ReleasePagePin(page, g.pinId)
+}
+
+func (g *WriteGuard) Page() *storage.Page {
+	return g.page.Page
+}
+
+// Release marks the page dirty, unlatches and unpins it. Calling it more than once is a no-op.
+func (g *WriteGuard) Release() error {
+	if g.page == nil {
+		return nil
+	}
+
+	page := g.page
+	g.page = nil
+	page.SetDirty()
+	page.Latch.Unlock()
+	return g.manager.ReleasePagePin(page, g.pinId)
+}
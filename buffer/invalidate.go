@@ -0,0 +1,96 @@
+package buffer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrRelationPagesPinned = errors.New("relation pages are still pinned")
+)
+
+// InvalidateRelation discards all frames holding pages of a relation, without writing dirty ones.
+// Pinned pages are waited for until ctx is done. Callers must prevent new accesses to the relation.
+func (m *Manager) InvalidateRelation(ctx context.Context, relation string) error {
+	for {
+		// Register as waiter before discarding so that no release happening in between is missed
+		m.frameWaiters.Add(1)
+		m.waitMutex.Lock()
+		freed := m.frameFreed
+		m.waitMutex.Unlock()
+
+		if m.discardRelationFrames(relation) == 0 {
+			m.frameWaiters.Add(-1)
+			return nil
+		}
+
+		select {
+		case <-freed:
+			m.frameWaiters.Add(-1)
+		case <-ctx.Done():
+			m.frameWaiters.Add(-1)
+			return fmt.Errorf("%w: %w", ErrRelationPagesPinned, ctx.Err())
+		}
+	}
+}
+
+// DropRelation discards the buffered pages of a relation, then unregisters and deletes its file.
+func (m *Manager) DropRelation(ctx context.Context, relation string) error {
+	fpath, err := m.directory.GetFilePath(relation)
+	if err != nil {
+		return err
+	}
+
+	if err := m.InvalidateRelation(ctx, relation); err != nil {
+		return err
+	}
+	if err := m.directory.UnregisterFile(relation); err != nil {
+		return fmt.Errorf("failed to unregister relation file: %w", err)
+	}
+	return m.store.DeleteFile(fpath)
+}
+
+// TruncateRelation discards the buffered pages of a relation, then unregisters its pages and empties its file.
+func (m *Manager) TruncateRelation(ctx context.Context, relation string) error {
+	fpath, err := m.directory.GetFilePath(relation)
+	if err != nil {
+		return err
+	}
+
+	if err := m.InvalidateRelation(ctx, relation); err != nil {
+		return err
+	}
+	if err := m.directory.ClearPages(relation); err != nil {
+		return fmt.Errorf("failed to unregister relation pages: %w", err)
+	}
+	return m.store.TruncateFile(fpath)
+}
+
+// discardRelationFrames empties unpinned frames of a relation and returns the count of pinned ones left.
+func (m *Manager) discardRelationFrames(relation string) int {
+	pinned := 0
+	for i := range m.shards {
+		shard := &m.shards[i]
+		shard.mutex.Lock()
+		for pageId, frame := range shard.pages {
+			if pageId.Relation != relation {
+				continue
+			}
+
+			// Take the frame like an eviction would, lookups are excluded by the shard lock
+			if !frame.pinCount.CompareAndSwap(0, 1) {
+				pinned++
+				continue
+			}
+
+			delete(shard.pages, pageId)
+			frame.valid = false
+			frame.dirty.Store(false)
+			frame.Page = nil
+			m.unpin(frame)
+		}
+		shard.mutex.Unlock()
+	}
+	return pinned
+}
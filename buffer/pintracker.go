@@ -0,0 +1,134 @@
+package buffer
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tinydb/storage"
+)
+
+const (
+	maxPinStackDepth = 32
+)
+
+// PinId identifies a pin recorded by the pin tracker, it is zero when pin tracking isn't enabled.
+type PinId uint64
+
+// PinRecord describes an outstanding page pin recorded by the pin tracker.
+type PinRecord struct {
+	Id     PinId
+	PageId storage.PageId
+	Since  time.Time
+	frame  *BufferPage
+	stack  []uintptr
+}
+
+// Stack returns the formatted call stack of the goroutine that acquired the pin.
+func (r PinRecord) Stack() string {
+	var builder strings.Builder
+	frames := runtime.CallersFrames(r.stack)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&builder, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return builder.String()
+}
+
+// pinTracker records the call stack of every pin handed out by the manager.
+// A pin is released with the id it was given, dropping its own record.
+type pinTracker struct {
+	pins   map[PinId]PinRecord
+	lastId PinId
+	mutex  *sync.Mutex
+}
+
+// WithPinTracking enables the pin leak detection debug mode.
+// Every pin records its call stack, which is costly, and releasing a page that isn't pinned panics.
+func WithPinTracking() Option {
+	return func(m *Manager) {
+		m.pinTracker = &pinTracker{
+			pins:  map[PinId]PinRecord{},
+			mutex: &sync.Mutex{},
+		}
+	}
+}
+
+func (t *pinTracker) track(page *BufferPage) PinId {
+	if t == nil {
+		return 0
+	}
+
+	stack := make([]uintptr, maxPinStackDepth)
+	// Skip runtime.Callers, track and the manager method
+	stack = stack[:runtime.Callers(3, stack)]
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.lastId++
+	t.pins[t.lastId] = PinRecord{
+		Id:     t.lastId,
+		PageId: page.pageId,
+		Since:  time.Now(),
+		frame:  page,
+		stack:  stack,
+	}
+	return t.lastId
+}
+
+func (t *pinTracker) untrack(page *BufferPage, pinId PinId) {
+	if t == nil {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if record, found := t.pins[pinId]; !found || record.frame != page {
+		stack := make([]byte, 4096)
+		stack = stack[:runtime.Stack(stack, false)]
+		panic(fmt.Sprintf("buffer: release of page %s with pin %d which isn't held\n%s", page.pageId, pinId, stack))
+	}
+	delete(t.pins, pinId)
+}
+
+// LongHeldPins returns the outstanding pins acquired more than threshold ago, oldest first.
+// It returns nil if pin tracking isn't enabled.
+func (m *Manager) LongHeldPins(threshold time.Duration) []PinRecord {
+	if m.pinTracker == nil {
+		return nil
+	}
+
+	m.pinTracker.mutex.Lock()
+	defer m.pinTracker.mutex.Unlock()
+
+	var records []PinRecord
+	limit := time.Now().Add(-threshold)
+	for _, record := range m.pinTracker.pins {
+		if record.Since.Before(limit) {
+			records = append(records, record)
+		}
+	}
+
+	slices.SortFunc(records, func(a, b PinRecord) int {
+		return a.Since.Compare(b.Since)
+	})
+	return records
+}
+
+// DumpLongHeldPins writes a report of the pins acquired more than threshold ago.
+func (m *Manager) DumpLongHeldPins(w io.Writer, threshold time.Duration) error {
+	for _, record := range m.LongHeldPins(threshold) {
+		_, err := fmt.Fprintf(w, "page %s pinned for %s by:\n%s\n", record.PageId, time.Since(record.Since).Round(time.Millisecond), record.Stack())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package buffer
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/tinydb/storage"
+)
+
+// Stats is a snapshot of the buffer pool usage.
+// Counters are cumulative since the manager creation.
+type Stats struct {
+	Frames      int
+	UsedFrames  int
+	PinnedPages int
+	DirtyPages  int
+	PinCounts   map[int32]int // Pin count to number of pages having it
+
+	Hits        uint64
+	Misses      uint64
+	Evictions   uint64
+	DirtyWrites uint64 // Dirty pages written to storage
+	FrameWaits  uint64 // Times a caller had to wait for a frame to be unpinned
+}
+
+// HitRatio returns the share of page requests served without storage read.
+func (s Stats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// PageInfo describes a page held by the buffer pool.
+type PageInfo struct {
+	PageId     storage.PageId
+	PinCount   int32
+	Dirty      bool
+	LastAccess uint64 // Logical access time, higher is more recent
+}
+
+// Stats returns a snapshot of the buffer pool state.
+// Shards are visited one after the other, so the snapshot isn't atomic across the whole pool.
+func (m *Manager) Stats() Stats {
+	stats := Stats{
+		Frames:      len(m.frames),
+		PinCounts:   map[int32]int{},
+		DirtyWrites: m.dirtyWrites.Load(),
+		FrameWaits:  m.frameWaits.Load(),
+	}
+
+	for i := range m.shards {
+		shard := &m.shards[i]
+		shard.mutex.Lock()
+		stats.Hits += shard.hits
+		stats.Misses += shard.misses
+		stats.Evictions += shard.evictions
+		for _, page := range shard.pages {
+			stats.UsedFrames++
+			pinCount := page.pinCount.Load()
+			if pinCount != 0 {
+				stats.PinnedPages++
+			}
+			if page.dirty.Load() {
+				stats.DirtyPages++
+			}
+			stats.PinCounts[pinCount]++
+		}
+		shard.mutex.Unlock()
+	}
+
+	return stats
+}
+
+// Pages lists the pages held by the buffer pool, most recently accessed first.
+func (m *Manager) Pages() []PageInfo {
+	pages := make([]PageInfo, 0, len(m.frames))
+	for i := range m.shards {
+		shard := &m.shards[i]
+		shard.mutex.Lock()
+		for pageId, page := range shard.pages {
+			pages = append(pages, PageInfo{
+				PageId:     pageId,
+				PinCount:   page.pinCount.Load(),
+				Dirty:      page.dirty.Load(),
+				LastAccess: page.lastAccess.Load(),
+			})
+		}
+		shard.mutex.Unlock()
+	}
+
+	slices.SortFunc(pages, func(a, b PageInfo) int {
+		return cmp.Compare(b.LastAccess, a.LastAccess)
+	})
+	return pages
+}
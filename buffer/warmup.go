@@ -0,0 +1,109 @@
+package buffer
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tinydb/storage"
+)
+
+// Resident pages file format: one "<page id> <relation>" line per page, most recently accessed first.
+
+// SaveResidentPages writes the ids of the pages held by the pool to a file, for a later Prewarm.
+// The file is replaced atomically.
+func (m *Manager) SaveResidentPages(fpath string) error {
+	tmpFile, err := os.CreateTemp(path.Dir(fpath), path.Base(fpath)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+	defer tmpFile.Close()
+
+	writer := bufio.NewWriter(tmpFile)
+	for _, page := range m.Pages() {
+		if _, err := fmt.Fprintf(writer, "%d %s\n", page.PageId.Id, page.PageId.Relation); err != nil {
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	if err := tmpFile.Sync(); err != nil {
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), fpath)
+}
+
+// RecordResidentPages saves the resident pages every interval until ctx is done.
+// It is meant to run in its own goroutine and stops at the first failure.
+func (m *Manager) RecordResidentPages(ctx context.Context, fpath string, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := m.SaveResidentPages(fpath); err != nil {
+				return fmt.Errorf("failed to save resident pages: %w", err)
+			}
+		}
+	}
+}
+
+// Prewarm loads the pages listed by SaveResidentPages, up to the pool capacity.
+// Pages are loaded least recently accessed first, so that the most recently accessed ones
+// get the latest access ticks and are the last evicted. It is meant to run in its own goroutine at startup.
+// A missing file or pages which don't exist anymore are ignored.
+func (m *Manager) Prewarm(ctx context.Context, fpath string) error {
+	file, err := os.Open(fpath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	var pageIds []storage.PageId
+	scanner := bufio.NewScanner(file)
+	for len(pageIds) < len(m.frames) && scanner.Scan() {
+		idStr, relation, found := strings.Cut(scanner.Text(), " ")
+		if !found {
+			return fmt.Errorf("malformed resident page line %q", scanner.Text())
+		}
+		id, err := strconv.ParseUint(idStr, 10, 32)
+		if err != nil {
+			return fmt.Errorf("malformed resident page id: %w", err)
+		}
+		pageIds = append(pageIds, storage.PageId{Id: uint32(id), Relation: relation})
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	for i := len(pageIds) - 1; i >= 0; i-- {
+		page, pinId, err := m.GetPage(ctx, pageIds[i])
+		if err != nil {
+			if errors.Is(err, storage.ErrPageNotFound) || errors.Is(err, storage.ErrRelationNotExists) {
+				continue
+			}
+			return err
+		}
+		if err := m.ReleasePagePin(page, pinId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
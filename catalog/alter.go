@@ -0,0 +1,385 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/tinydb/heap"
+)
+
+var (
+	ErrFieldAlreadyExists = errors.New("field already exists")
+	ErrDefaultRequired    = errors.New("field isn't nullable and has no default value")
+	ErrNullValues         = errors.New("field has null values")
+	ErrLastField          = errors.New("cannot drop the last field of a relation")
+	ErrFieldIndexed       = errors.New("field is used by an index")
+	ErrTooManyVersions    = errors.New("relation reached the maximum layout versions count")
+	ErrIncompatibleType   = errors.New("field values cannot be converted to the requested type")
+	ErrNoStorage          = errors.New("catalog has no storage")
+
+	// Returned by alterations leaving the layout as is, no layout version is recorded then
+	errLayoutUnchanged = errors.New("layout unchanged")
+)
+
+// Field types changes allowed by SetFieldType. Values of those types are read as the same Go type,
+// values of tuples written before a change are converted to the new type precision when read.
+var typeConversions = map[FieldType][]FieldType{
+	DatetimeType:    {TimestampType, TimestampTzType, DateType},
+	TimestampType:   {TimestampTzType, DatetimeType, DateType},
+	TimestampTzType: {TimestampType, DatetimeType, DateType},
+	DateType:        {TimestampType, TimestampTzType, DatetimeType},
+}
+
+// Relations layouts are versioned: each change creates a new layout version and tuples hold the version
+// of the layout they were written with. Old tuples are never rewritten by an alteration, they are
+// read through DecodeTuple and upgraded when written again, see UpgradeTuple and UpgradeRecord.
+// Fields are matched across versions by their identifier, which is never reused within a relation.
+
+// AddField appends a field to the relation layout.
+// Tuples written before read the field default value, which is required if the field isn't nullable.
+func (l *Catalog) AddField(ctx context.Context, relation string, field Field) error {
+	if field.Default == nil && !field.Nullable {
+		return ErrDefaultRequired
+	}
+	if _, err := encodeDefault(field); err != nil {
+		return fmt.Errorf("invalid default value: %w", err)
+	}
+
+	return l.alterRelation(ctx, relation, func(relData *RelationData, fields []Field) ([]Field, error) {
+		if slices.ContainsFunc(fields, func(f Field) bool { return f.Name == field.Name }) {
+			return nil, ErrFieldAlreadyExists
+		}
+
+		var maxId uint16
+		for _, layout := range relData.layouts {
+			for _, f := range layout.Fields {
+				maxId = max(maxId, f.Id)
+			}
+		}
+		field.Id = maxId + 1
+		return append(fields, field), nil
+	})
+}
+
+// DropField removes a field from the relation layout. Fields used by an index cannot be dropped.
+func (l *Catalog) DropField(ctx context.Context, relation string, name string) error {
+	return l.alterRelation(ctx, relation, func(relData *RelationData, fields []Field) ([]Field, error) {
+		i, err := fieldIndex(*relData, fields, name)
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) == 1 {
+			return nil, ErrLastField
+		}
+		return slices.Delete(fields, i, i+1), nil
+	})
+}
+
+// RenameField changes the name of a field, along with the definitions of the indexes using it.
+func (l *Catalog) RenameField(ctx context.Context, relation string, name string, newName string) error {
+	return l.alterRelation(ctx, relation, func(relData *RelationData, fields []Field) ([]Field, error) {
+		i := slices.IndexFunc(fields, func(f Field) bool { return f.Name == name })
+		if i == -1 {
+			return nil, ErrFieldNotFound
+		}
+		if slices.ContainsFunc(fields, func(f Field) bool { return f.Name == newName }) {
+			return nil, ErrFieldAlreadyExists
+		}
+		fields[i].Name = newName
+
+		indexes := slices.Clone(relData.indexes)
+		for j, index := range indexes {
+			if !slices.Contains(index.Columns, name) {
+				continue
+			}
+			index.Columns = slices.Clone(index.Columns)
+			for k, column := range index.Columns {
+				if column == name {
+					index.Columns[k] = newName
+				}
+			}
+			indexes[j] = index
+		}
+		relData.indexes = indexes
+		return fields, nil
+	})
+}
+
+// SetFieldNullable changes the nullability of a field.
+// For a persisted catalog, a field cannot be made non nullable while the relation holds null values for it.
+func (l *Catalog) SetFieldNullable(ctx context.Context, relation string, name string, nullable bool) error {
+	return l.alterRelation(ctx, relation, func(relData *RelationData, fields []Field) ([]Field, error) {
+		i := slices.IndexFunc(fields, func(f Field) bool { return f.Name == name })
+		if i == -1 {
+			return nil, ErrFieldNotFound
+		}
+		if fields[i].Nullable == nullable {
+			return nil, errLayoutUnchanged
+		}
+
+		if !nullable && l.heap != nil {
+			err := l.checkValues(ctx, relation, *relData, fields[i].Id, func(value any) error {
+				if value == nil {
+					return ErrNullValues
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		fields[i].Nullable = nullable
+		return fields, nil
+	})
+}
+
+// SetFieldType changes the type of a field, such as a datetime to a timestamp, see typeConversions.
+// Fields used by an index cannot be changed. For a persisted catalog, all the relation values must fit the new type.
+func (l *Catalog) SetFieldType(ctx context.Context, relation string, name string, fieldType FieldType) error {
+	return l.alterRelation(ctx, relation, func(relData *RelationData, fields []Field) ([]Field, error) {
+		i, err := fieldIndex(*relData, fields, name)
+		if err != nil {
+			return nil, err
+		}
+		if fields[i].Type == fieldType {
+			return nil, errLayoutUnchanged
+		}
+		if !slices.Contains(typeConversions[fields[i].Type], fieldType) {
+			return nil, fmt.Errorf("%w: %s to %s", ErrIncompatibleType, fields[i].Type, fieldType)
+		}
+
+		converted := fields[i]
+		converted.Type = fieldType
+		if _, err := encodeDefault(converted); err != nil {
+			return nil, fmt.Errorf("%w: default value: %w", ErrIncompatibleType, err)
+		}
+		if l.heap != nil {
+			layout, err := NewLayout([]Field{{Type: fieldType, Nullable: true}})
+			if err != nil {
+				return nil, err
+			}
+			err = l.checkValues(ctx, relation, *relData, fields[i].Id, func(value any) error {
+				if _, err := layout.EncodeRow([]any{value}); err != nil {
+					return fmt.Errorf("%w: %w", ErrIncompatibleType, err)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		fields[i] = converted
+		return fields, nil
+	})
+}
+
+// DecodeTuple reads all values of a tuple written with any layout version of the relation,
+// in current layout fields order. Fields added after the tuple was written get their default value.
+func (l *Catalog) DecodeTuple(relation string, tuple []byte) ([]any, error) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	relData, found := l.relations[relation]
+	if !found {
+		return nil, ErrLayoutNotFound
+	}
+	return decodeVersionedRow(relData, tuple)
+}
+
+// UpgradeTuple re-encodes a tuple written with a past layout version using the current layout.
+// The tuple is returned as is, along with false, when it already uses the current layout.
+func (l *Catalog) UpgradeTuple(relation string, tuple []byte) ([]byte, bool, error) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	relData, found := l.relations[relation]
+	if !found {
+		return nil, false, ErrLayoutNotFound
+	}
+	layout := relData.layout()
+	version, err := ReadLayoutVersion(tuple)
+	if err != nil {
+		return nil, false, err
+	}
+	if version == layout.Version {
+		return tuple, false, nil
+	}
+
+	values, err := decodeVersionedRow(relData, tuple)
+	if err != nil {
+		return nil, false, err
+	}
+	upgraded, err := layout.EncodeRow(values)
+	if err != nil {
+		return nil, false, err
+	}
+	return upgraded, true, nil
+}
+
+// UpgradeRecord rewrites a stored tuple of the relation with the current layout, if it uses a past version.
+// The upgraded tuple may have another size and be moved, its record id is returned.
+func (l *Catalog) UpgradeRecord(ctx context.Context, relation string, recordId heap.RecordId) (heap.RecordId, error) {
+	if l.heap == nil {
+		return heap.RecordId{}, ErrNoStorage
+	}
+
+	tuple, err := l.heap.Read(ctx, recordId)
+	if err != nil {
+		return heap.RecordId{}, fmt.Errorf("failed to read tuple %s: %w", recordId, err)
+	}
+	upgraded, changed, err := l.UpgradeTuple(relation, tuple)
+	if err != nil || !changed {
+		return recordId, err
+	}
+	return l.heap.Rewrite(ctx, recordId, upgraded)
+}
+
+// alterRelation records the next layout version of a relation, built from a copy of the current fields.
+// The alteration may also replace index definitions, which are stored after the layout.
+// Nothing is recorded when the alteration returns errLayoutUnchanged.
+func (l *Catalog) alterRelation(ctx context.Context, relation string, alter func(relData *RelationData, fields []Field) ([]Field, error)) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	relData, found := l.relations[relation]
+	if !found {
+		return ErrLayoutNotFound
+	}
+	current := relData.layout()
+	indexes := relData.indexes
+	fields, err := alter(&relData, slices.Clone(current.Fields))
+	if errors.Is(err, errLayoutUnchanged) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if current.Version == math.MaxUint16 {
+		return ErrTooManyVersions
+	}
+	layout, err := NewLayout(fields)
+	if err != nil {
+		return err
+	}
+	layout.Version = current.Version + 1
+
+	if l.heap != nil {
+		if err := l.persistLayoutVersion(ctx, relation, &relData.records, layout); err != nil {
+			return err
+		}
+	}
+	relData.layouts = append(slices.Clone(relData.layouts), layout)
+
+	var indexErr error
+	if l.heap != nil {
+		// Index definitions naming renamed fields are also fixed when the catalog is loaded
+		indexErr = l.persistIndexChanges(ctx, relation, &relData.records, indexes, relData.indexes)
+	}
+	l.relations[relation] = relData
+	if indexErr != nil {
+		return fmt.Errorf("layout changed but index definitions weren't stored: %w", indexErr)
+	}
+	return nil
+}
+
+// checkValues scans the relation and checks the values of the given field.
+func (l *Catalog) checkValues(ctx context.Context, relation string, relData RelationData, id uint16, check func(value any) error) error {
+	i := slices.IndexFunc(relData.layout().Fields, func(f Field) bool { return f.Id == id })
+	return l.heap.Scan(ctx, relation, func(recordId heap.RecordId, tuple []byte) error {
+		values, err := decodeVersionedRow(relData, tuple)
+		if err != nil {
+			return fmt.Errorf("failed to read tuple %s: %w", recordId, err)
+		}
+		if err := check(values[i]); err != nil {
+			return fmt.Errorf("tuple %s: %w", recordId, err)
+		}
+		return nil
+	})
+}
+
+// fieldIndex returns the position of a field which isn't used by any index of the relation.
+func fieldIndex(relData RelationData, fields []Field, name string) (int, error) {
+	i := slices.IndexFunc(fields, func(f Field) bool { return f.Name == name })
+	if i == -1 {
+		return 0, ErrFieldNotFound
+	}
+	for _, index := range relData.indexes {
+		if slices.Contains(index.Columns, name) {
+			return 0, fmt.Errorf("%w: %s", ErrFieldIndexed, index.Name)
+		}
+	}
+	return i, nil
+}
+
+func decodeVersionedRow(relData RelationData, tuple []byte) ([]any, error) {
+	version, err := ReadLayoutVersion(tuple)
+	if err != nil {
+		return nil, err
+	}
+	if int(version) >= len(relData.layouts) {
+		return nil, ErrLayoutVersionNotFound
+	}
+	tupleLayout := relData.layouts[version]
+	tupleValues, err := tupleLayout.DecodeRow(tuple)
+	if err != nil {
+		return nil, err
+	}
+
+	layout := relData.layout()
+	if version == layout.Version {
+		return tupleValues, nil
+	}
+	values := make([]any, len(layout.Fields))
+	for i, field := range layout.Fields {
+		j := slices.IndexFunc(tupleLayout.Fields, func(f Field) bool { return f.Id == field.Id })
+		switch {
+		case j == -1:
+			values[i] = field.Default
+		case tupleLayout.Fields[j].Type != field.Type && tupleValues[j] != nil:
+			value, err := field.convertValue(tupleValues[j])
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert field %s: %w", field.Name, err)
+			}
+			values[i] = value
+		default:
+			values[i] = tupleValues[j]
+		}
+	}
+	return values, nil
+}
+
+// convertValue returns a value read with another field type, see typeConversions,
+// as it is read once written with the field type.
+func (f Field) convertValue(value any) (any, error) {
+	t, ok := value.(time.Time)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T to %s", ErrIncompatibleType, value, f.Type)
+	}
+
+	switch f.Type {
+	case DatetimeType:
+		return time.Unix(t.Unix(), 0), nil
+	case DateType:
+		days, err := dateDays(t)
+		if err != nil {
+			return nil, err
+		}
+		return daysDate(days), nil
+	case TimestampType, TimestampTzType:
+		nanoseconds, err := timestampNanoseconds(t)
+		if err != nil {
+			return nil, err
+		}
+		if f.Type == TimestampType {
+			return time.Unix(0, nanoseconds).UTC(), nil
+		}
+		_, offset := t.Zone()
+		return time.Unix(0, nanoseconds).In(time.FixedZone("", offset)), nil
+	default:
+		return nil, fmt.Errorf("%w: %T to %s", ErrIncompatibleType, value, f.Type)
+	}
+}
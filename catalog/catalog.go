@@ -1,42 +1,230 @@
 package catalog
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/tinydb/buffer"
+	"github.com/tinydb/heap"
+)
 
 var (
-	ErrLayoutNotFound = errors.New("layout not found")
+	ErrLayoutNotFound          = errors.New("layout not found")
+	ErrLayoutVersionNotFound   = errors.New("layout version not found")
+	ErrRelationAlreadyExists   = errors.New("relation already exists")
+	ErrIndexAlreadyExists      = errors.New("index already exists")
+	ErrReservedRelationName    = errors.New("relation name is reserved for system relations")
+	ErrInvalidIndexDefinition  = errors.New("index must have a name and at least one existing column")
+	ErrSystemRelationCorrupted = errors.New("system relation content is invalid")
 )
 
+type Index struct {
+	Name    string
+	Columns []string
+	Unique  bool
+}
+
 type RelationData struct {
-	layout Layout
-	// index
+	layouts []Layout // All layout versions, indexed by version
+	indexes []Index
 	// constraints
+
+	records relationRecords
+}
+
+// layout returns the current layout, used to write tuples.
+func (r RelationData) layout() Layout {
+	return r.layouts[len(r.layouts)-1]
+}
+
+// relationRecords locates the system relations tuples describing a relation.
+type relationRecords struct {
+	table   heap.RecordId
+	columns []heap.RecordId // Of all layout versions
+	indexes []heap.RecordId
 }
 
 // Catalog is the storage of relations layout and index info.
+// A catalog created with OpenCatalog is persisted in system relations, see system.go.
 type Catalog struct {
 	relations map[string]RelationData
+	heap      *heap.Manager // Nil for an in-memory catalog
+	buffers   *buffer.Manager
+	mutex     *sync.RWMutex
 }
 
+// NewCatalog returns an in-memory catalog.
 func NewCatalog() *Catalog {
 	return &Catalog{
 		relations: map[string]RelationData{},
+		mutex:     &sync.RWMutex{},
 	}
 }
 
-// todo: load all catalog from disk at init time
+// OpenCatalog opens the system relations, creating them if needed, and loads the catalog they hold.
+func OpenCatalog(ctx context.Context, heapManager *heap.Manager, buffers *buffer.Manager) (*Catalog, error) {
+	catalog := &Catalog{
+		relations: map[string]RelationData{},
+		heap:      heapManager,
+		buffers:   buffers,
+		mutex:     &sync.RWMutex{},
+	}
+
+	for _, relation := range systemRelations {
+		if err := heapManager.OpenRelation(ctx, relation, relation); err != nil {
+			return nil, fmt.Errorf("failed to open system relation %s: %w", relation, err)
+		}
+	}
+	if err := catalog.load(ctx); err != nil {
+		return nil, fmt.Errorf("failed to load catalog: %w", err)
+	}
+	return catalog, nil
+}
 
 func (l *Catalog) GetLayout(relation string) (Layout, error) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
 	relData, found := l.relations[relation]
 	if found {
-		return relData.layout, nil
+		return relData.layout(), nil
 	}
 
 	return Layout{}, ErrLayoutNotFound
 }
 
-func (l *Catalog) SetLayout(relation string, layout Layout) error {
-	relData := l.relations[relation]
-	relData.layout = layout
+// GetLayoutVersion returns a past or current layout of a relation.
+func (l *Catalog) GetLayoutVersion(relation string, version uint16) (Layout, error) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	relData, found := l.relations[relation]
+	if !found {
+		return Layout{}, ErrLayoutNotFound
+	}
+	if int(version) >= len(relData.layouts) {
+		return Layout{}, ErrLayoutVersionNotFound
+	}
+	return relData.layouts[version], nil
+}
+
+func (l *Catalog) GetIndexes(relation string) ([]Index, error) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	relData, found := l.relations[relation]
+	if !found {
+		return nil, ErrLayoutNotFound
+	}
+	return slices.Clone(relData.indexes), nil
+}
+
+// Relations returns the names of all relations of the catalog.
+func (l *Catalog) Relations() []string {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	relations := make([]string, 0, len(l.relations))
+	for relation := range l.relations {
+		relations = append(relations, relation)
+	}
+	return relations
+}
+
+// AddRelation records the layout of a new relation, as its first version.
+// Fields are assigned their identifiers.
+func (l *Catalog) AddRelation(ctx context.Context, relation string, layout Layout) error {
+	if IsSystemRelation(relation) {
+		return ErrReservedRelationName
+	}
+	if len(layout.Fields) == 0 {
+		return ErrEmptyLayout
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if _, exists := l.relations[relation]; exists {
+		return ErrRelationAlreadyExists
+	}
+
+	layout.Version = 0
+	layout.Fields = slices.Clone(layout.Fields)
+	for i := range layout.Fields {
+		layout.Fields[i].Id = uint16(i + 1)
+	}
+
+	relData := RelationData{
+		layouts: []Layout{layout},
+	}
+	if l.heap != nil {
+		records, err := l.persistRelation(ctx, relation, layout)
+		if err != nil {
+			return err
+		}
+		relData.records = records
+	}
+
+	l.relations[relation] = relData
+	return nil
+}
+
+// RemoveRelation forgets a relation along with its indexes.
+func (l *Catalog) RemoveRelation(ctx context.Context, relation string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	relData, found := l.relations[relation]
+	if !found {
+		return ErrLayoutNotFound
+	}
+
+	if l.heap != nil {
+		if err := l.unpersistRelation(ctx, relData.records); err != nil {
+			return err
+		}
+	}
+
+	delete(l.relations, relation)
+	return nil
+}
+
+// AddIndex records the definition of an index on a relation.
+func (l *Catalog) AddIndex(ctx context.Context, relation string, index Index) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	relData, found := l.relations[relation]
+	if !found {
+		return ErrLayoutNotFound
+	}
+	if index.Name == "" || len(index.Columns) == 0 {
+		return ErrInvalidIndexDefinition
+	}
+	layout := relData.layout()
+	for _, column := range index.Columns {
+		if _, err := layout.GetField(column); err != nil {
+			return ErrInvalidIndexDefinition
+		}
+	}
+	for _, existing := range relData.indexes {
+		if existing.Name == index.Name {
+			return ErrIndexAlreadyExists
+		}
+	}
+
+	if l.heap != nil {
+		recordId, err := l.persistIndex(ctx, relation, index)
+		if err != nil {
+			return err
+		}
+		relData.records.indexes = append(relData.records.indexes, recordId)
+	}
+
+	relData.indexes = append(relData.indexes, index)
 	l.relations[relation] = relData
 	return nil
 }
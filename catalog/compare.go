@@ -0,0 +1,297 @@
+package catalog
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"slices"
+	"time"
+
+	"github.com/tinydb/data"
+)
+
+var (
+	ErrNotComparable = errors.New("field type has no comparator")
+)
+
+// NullOrder places null values before or after all other values.
+type NullOrder uint8
+
+const (
+	NullsFirst NullOrder = iota
+	NullsLast
+)
+
+// Collation orders string values, given as their UTF-8 bytes.
+type Collation func(a []byte, b []byte) int
+
+// BinaryCollation orders strings byte by byte, the default collation.
+var BinaryCollation Collation = bytes.Compare
+
+type CompareOptions struct {
+	Nulls     NullOrder
+	Collation Collation // Used for string fields, BinaryCollation if nil
+}
+
+// Comparator compares the values of a field in encoded tuples, without decoding them.
+// Floats are ordered with NaN greater than any number and equal to NaN, enums in labels order,
+// timestamps with time zone by instant and decimals by numeric value.
+type Comparator struct {
+	field   Field
+	options CompareOptions
+	size    uint16                // Encoded size of fixed size values
+	compare func(a, b []byte) int // Compares encoded values
+}
+
+func NewComparator(field Field, options CompareOptions) (Comparator, error) {
+	if options.Collation == nil {
+		options.Collation = BinaryCollation
+	}
+	comparator := Comparator{
+		field:   field,
+		options: options,
+	}
+
+	if field.custom != nil {
+		if field.custom.Compare == nil {
+			return Comparator{}, fmt.Errorf("%w: %s", ErrNotComparable, field.Type)
+		}
+		comparator.size = field.custom.Info.Size
+		comparator.compare = field.custom.Compare
+		return comparator, nil
+	}
+
+	info, found := TypesInfoMap[field.Type]
+	if !found {
+		return Comparator{}, ErrUnknownFieldType
+	}
+	comparator.size = info.Size
+	switch field.Type {
+	case BoolType:
+		// Packed, compared in CompareTuples
+	case Int8Type, Int16Type, Int32Type, Int64Type, DatetimeType, DateType, TimeType:
+		comparator.compare = data.CompareSigned
+	case Uint8Type, Uint16Type, Uint32Type, Uint64Type, EnumType, UUIDType, BytesType:
+		comparator.compare = data.CompareUnsigned
+	case Float32Type:
+		comparator.compare = data.CompareFloat32
+	case Float64Type:
+		comparator.compare = data.CompareFloat64
+	case TimestampType, TimestampTzType:
+		// Instant only, the UTC offset is ignored
+		comparator.size = 8
+		comparator.compare = data.CompareSigned
+	case DecimalType:
+		comparator.compare = compareEncodedDecimals
+	case StringType:
+		comparator.compare = options.Collation
+	default:
+		return Comparator{}, fmt.Errorf("%w: %s", ErrNotComparable, field.Type)
+	}
+	return comparator, nil
+}
+
+// CompareTuples compares the field values of two tuples written with the field layout,
+// returning a negative number, zero or a positive number.
+func (c Comparator) CompareTuples(a []byte, b []byte) (int, error) {
+	f := c.field
+	if f.Nullable {
+		aNull, err := f.IsNull(a)
+		if err != nil {
+			return 0, err
+		}
+		bNull, err := f.IsNull(b)
+		if err != nil {
+			return 0, err
+		}
+		if aNull || bNull {
+			return c.compareNulls(aNull, bNull), nil
+		}
+	}
+
+	if f.packed {
+		if int(f.offset) >= len(a) || int(f.offset) >= len(b) {
+			return 0, data.ErrOutOfBounds
+		}
+		return data.CompareBits(a[f.offset], b[f.offset], f.packIndex), nil
+	}
+
+	aValue, err := c.encodedValue(a)
+	if err != nil {
+		return 0, err
+	}
+	bValue, err := c.encodedValue(b)
+	if err != nil {
+		return 0, err
+	}
+	return c.compare(aValue, bValue), nil
+}
+
+// CompareValues compares decoded values of the field, as returned by Field.Read or Layout.DecodeRow.
+func (c Comparator) CompareValues(a any, b any) (int, error) {
+	if a == nil || b == nil {
+		return c.compareNulls(a == nil, b == nil), nil
+	}
+	if c.field.custom != nil {
+		return c.compareCustomValues(a, b)
+	}
+
+	switch typedA := a.(type) {
+	case bool:
+		return compareValues(typedA, b, func(a, b bool) int { return cmp.Compare(boolOrder(a), boolOrder(b)) })
+	case int8:
+		return compareValues(typedA, b, cmp.Compare[int8])
+	case int16:
+		return compareValues(typedA, b, cmp.Compare[int16])
+	case int32:
+		return compareValues(typedA, b, cmp.Compare[int32])
+	case int64:
+		return compareValues(typedA, b, cmp.Compare[int64])
+	case uint8:
+		return compareValues(typedA, b, cmp.Compare[uint8])
+	case uint16:
+		return compareValues(typedA, b, cmp.Compare[uint16])
+	case uint32:
+		return compareValues(typedA, b, cmp.Compare[uint32])
+	case uint64:
+		return compareValues(typedA, b, cmp.Compare[uint64])
+	case float32:
+		return compareValues(typedA, b, func(a, b float32) int { return compareFloats(float64(a), float64(b)) })
+	case float64:
+		return compareValues(typedA, b, compareFloats)
+	case time.Time:
+		return compareValues(typedA, b, time.Time.Compare)
+	case Decimal:
+		return compareValues(typedA, b, compareDecimals)
+	case UUID:
+		return compareValues(typedA, b, func(a, b UUID) int { return bytes.Compare(a[:], b[:]) })
+	case TimeOfDay:
+		return compareValues(typedA, b, cmp.Compare[TimeOfDay])
+	case []byte:
+		return compareValues(typedA, b, bytes.Compare)
+	case string:
+		if c.field.Type == EnumType {
+			return compareValues(typedA, b, func(a, b string) int {
+				return cmp.Compare(slices.Index(c.field.Labels, a), slices.Index(c.field.Labels, b))
+			})
+		}
+		return compareValues(typedA, b, func(a, b string) int { return c.options.Collation([]byte(a), []byte(b)) })
+	default:
+		return 0, fmt.Errorf("%w: %T", ErrNotComparable, a)
+	}
+}
+
+func (c Comparator) compareNulls(aNull bool, bNull bool) int {
+	switch {
+	case aNull && bNull:
+		return 0
+	case aNull == (c.options.Nulls == NullsFirst):
+		return -1
+	default:
+		return 1
+	}
+}
+
+// encodedValue returns the encoded value of the field in a tuple.
+func (c Comparator) encodedValue(tuple []byte) ([]byte, error) {
+	f := c.field
+	if !f.variableLength() {
+		if int(f.offset)+int(c.size) > len(tuple) {
+			return nil, data.ErrOutOfBounds
+		}
+		return tuple[f.offset : f.offset+c.size], nil
+	}
+
+	strData, err := readString(tuple, f.offset)
+	if err != nil {
+		return nil, err
+	}
+	if strData.Overflow.PageId != 0 {
+		return nil, fmt.Errorf("%w: field %s", ErrOverflowString, f.Name)
+	}
+	return strData.StrBytes, nil
+}
+
+func (c Comparator) compareCustomValues(a any, b any) (int, error) {
+	aEncoded, err := c.field.custom.Encode(a)
+	if err != nil {
+		return 0, err
+	}
+	bEncoded, err := c.field.custom.Encode(b)
+	if err != nil {
+		return 0, err
+	}
+	return c.compare(aEncoded, bEncoded), nil
+}
+
+func compareValues[T any](a T, b any, compare func(a, b T) int) (int, error) {
+	typedB, ok := b.(T)
+	if !ok {
+		return 0, fmt.Errorf("%w: cannot compare %T with %T", ErrWrongFieldType, a, b)
+	}
+	return compare(a, typedB), nil
+}
+
+func boolOrder(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// compareFloats orders NaN after any number, as data.CompareFloat64.
+func compareFloats(a float64, b float64) int {
+	aNaN, bNaN := math.IsNaN(a), math.IsNaN(b)
+	if aNaN || bNaN {
+		return cmp.Compare(boolOrder(aNaN), boolOrder(bNaN))
+	}
+	return cmp.Compare(a, b)
+}
+
+func compareEncodedDecimals(a []byte, b []byte) int {
+	decode := func(encoded []byte) Decimal {
+		coefficient, _ := data.ReadInt64(encoded, 0)
+		return Decimal{Coefficient: coefficient, Scale: encoded[8]}
+	}
+	return compareDecimals(decode(a), decode(b))
+}
+
+// compareDecimals compares decimals numerically, 1.50 being equal to 1.5.
+func compareDecimals(a Decimal, b Decimal) int {
+	if a.Scale == b.Scale {
+		return cmp.Compare(a.Coefficient, b.Coefficient)
+	}
+	if a.Scale > b.Scale {
+		return -compareDecimals(b, a)
+	}
+
+	aSign, bSign := cmp.Compare(a.Coefficient, 0), cmp.Compare(b.Coefficient, 0)
+	if aSign != bSign || aSign == 0 {
+		return cmp.Compare(aSign, bSign)
+	}
+
+	// Scale a up to b scale, a magnitude beyond int64 range being beyond any b value
+	magnitude := uint64(a.Coefficient)
+	if a.Coefficient < 0 {
+		magnitude = -magnitude
+	}
+	for range b.Scale - a.Scale {
+		high, low := bits.Mul64(magnitude, 10)
+		if high != 0 || low > math.MaxInt64 {
+			// Out of range: |a| > |b|
+			if a.Coefficient < 0 {
+				return -1
+			}
+			return 1
+		}
+		magnitude = low
+	}
+	scaled := int64(magnitude)
+	if a.Coefficient < 0 {
+		scaled = -scaled
+	}
+	return cmp.Compare(scaled, b.Coefficient)
+}
@@ -0,0 +1,349 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"slices"
+	"time"
+
+	"github.com/tinydb/data"
+)
+
+var (
+	ErrNotKeyable = errors.New("field type cannot be used in keys")
+	ErrEmptyKey   = errors.New("key requires at least one column")
+)
+
+// KeyColumn is a field of a key, in ascending order unless Descending.
+// Nulls sets the position of null values in the key order, whatever the direction.
+type KeyColumn struct {
+	Field      string
+	Descending bool
+	Nulls      NullOrder
+}
+
+// KeyEncoder builds keys of layout fields whose byte order, as given by bytes.Compare,
+// matches the order of their values, see Comparator. Each column is written as a null marker
+// followed by the value, bitwise inverted for descending columns.
+// Timestamps with time zone are keyed by instant and decimals by numeric value: decoding returns
+// them in UTC and with the smallest scale. Registered types cannot be used in keys.
+type KeyEncoder struct {
+	layout  Layout
+	columns []KeyColumn
+	fields  []int // Layout index of each column field
+}
+
+func NewKeyEncoder(layout Layout, columns []KeyColumn) (KeyEncoder, error) {
+	if len(columns) == 0 {
+		return KeyEncoder{}, ErrEmptyKey
+	}
+
+	fields := make([]int, len(columns))
+	for i, column := range columns {
+		index := slices.IndexFunc(layout.Fields, func(f Field) bool { return f.Name == column.Field })
+		if index == -1 {
+			return KeyEncoder{}, fmt.Errorf("%w: %s", ErrFieldNotFound, column.Field)
+		}
+		field := layout.Fields[index]
+		if field.custom != nil {
+			return KeyEncoder{}, fmt.Errorf("%w: %s", ErrNotKeyable, field.Type)
+		}
+		fields[i] = index
+	}
+	return KeyEncoder{
+		layout:  layout,
+		columns: slices.Clone(columns),
+		fields:  fields,
+	}, nil
+}
+
+// EncodeKey builds a key from values given in key columns order, nil values being null.
+func (k KeyEncoder) EncodeKey(values []any) ([]byte, error) {
+	if len(values) != len(k.columns) {
+		return nil, fmt.Errorf("%w: expected %d key values, got %d", ErrValuesCount, len(k.columns), len(values))
+	}
+
+	var key []byte
+	for i, column := range k.columns {
+		field := k.layout.Fields[k.fields[i]]
+		value := values[i]
+		if err := field.checkValue(value); err != nil {
+			return nil, err
+		}
+
+		if value == nil {
+			marker := data.KeyNullFirst
+			if column.Nulls == NullsLast {
+				marker = data.KeyNullLast
+			}
+			key = append(key, marker)
+			continue
+		}
+
+		key = append(key, data.KeyNotNull)
+		start := len(key)
+		var err error
+		key, err = field.appendKey(key, value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode key field %s: %w", field.Name, err)
+		}
+		if column.Descending {
+			data.InvertKey(key[start:])
+		}
+	}
+	return key, nil
+}
+
+// EncodeTupleKey builds the key of a tuple written with the encoder layout.
+func (k KeyEncoder) EncodeTupleKey(tuple []byte) ([]byte, error) {
+	row, err := k.layout.DecodeRow(tuple)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]any, len(k.fields))
+	for i, index := range k.fields {
+		values[i] = row[index]
+	}
+	return k.EncodeKey(values)
+}
+
+// DecodeKey reads the values of a key, in key columns order.
+func (k KeyEncoder) DecodeKey(key []byte) ([]any, error) {
+	values := make([]any, len(k.columns))
+	for i, column := range k.columns {
+		field := k.layout.Fields[k.fields[i]]
+		if len(key) == 0 {
+			return nil, data.ErrInvalidKey
+		}
+		marker := key[0]
+		key = key[1:]
+		switch marker {
+		case data.KeyNullFirst, data.KeyNullLast:
+			continue
+		case data.KeyNotNull:
+		default:
+			return nil, data.ErrInvalidKey
+		}
+
+		part := key
+		if column.Descending {
+			part = slices.Clone(key)
+			data.InvertKey(part)
+		}
+		value, rest, err := field.readKey(part)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode key field %s: %w", field.Name, err)
+		}
+		values[i] = value
+		key = key[len(key)-len(rest):]
+	}
+	if len(key) != 0 {
+		return nil, data.ErrInvalidKey
+	}
+	return values, nil
+}
+
+// appendKey appends the memcomparable encoding of a value checked with checkValue.
+func (f Field) appendKey(key []byte, value any) ([]byte, error) {
+	switch typedVal := value.(type) {
+	case bool:
+		var b uint64
+		if typedVal {
+			b = 1
+		}
+		return data.AppendKeyUint(key, b, 1), nil
+	case int8:
+		return data.AppendKeyInt(key, int64(typedVal), 1), nil
+	case int16:
+		return data.AppendKeyInt(key, int64(typedVal), 2), nil
+	case int32:
+		return data.AppendKeyInt(key, int64(typedVal), 4), nil
+	case int64:
+		return data.AppendKeyInt(key, typedVal, 8), nil
+	case uint8:
+		return data.AppendKeyUint(key, uint64(typedVal), 1), nil
+	case uint16:
+		return data.AppendKeyUint(key, uint64(typedVal), 2), nil
+	case uint32:
+		return data.AppendKeyUint(key, uint64(typedVal), 4), nil
+	case uint64:
+		return data.AppendKeyUint(key, typedVal, 8), nil
+	case float32:
+		return data.AppendKeyFloat32(key, typedVal), nil
+	case float64:
+		return data.AppendKeyFloat64(key, typedVal), nil
+	case string:
+		if f.Type == EnumType {
+			return data.AppendKeyUint(key, uint64(slices.Index(f.Labels, typedVal)), 2), nil
+		}
+		return data.AppendKeyBytes(key, []byte(typedVal)), nil
+	case []byte:
+		return data.AppendKeyBytes(key, typedVal), nil
+	case UUID:
+		return append(key, typedVal[:]...), nil
+	case TimeOfDay:
+		if err := typedVal.validate(); err != nil {
+			return nil, err
+		}
+		return data.AppendKeyInt(key, int64(typedVal), 8), nil
+	case Decimal:
+		if err := typedVal.validate(); err != nil {
+			return nil, err
+		}
+		high, low := decimalKey(typedVal)
+		key = data.AppendKeyUint(key, high, 8)
+		return data.AppendKeyUint(key, low, 8), nil
+	case time.Time:
+		switch f.Type {
+		case DateType:
+			days, err := dateDays(typedVal)
+			if err != nil {
+				return nil, err
+			}
+			return data.AppendKeyInt(key, int64(days), 4), nil
+		case TimestampType, TimestampTzType:
+			nanoseconds, err := timestampNanoseconds(typedVal)
+			if err != nil {
+				return nil, err
+			}
+			return data.AppendKeyInt(key, nanoseconds, 8), nil
+		default:
+			return data.AppendKeyInt(key, typedVal.Unix(), 8), nil
+		}
+	default:
+		return nil, ErrWrongFieldType
+	}
+}
+
+// readKey reads a value written by appendKey, returning it along with the rest of the key.
+func (f Field) readKey(key []byte) (any, []byte, error) {
+	switch f.Type {
+	case BoolType:
+		b, rest, err := data.ReadKeyUint(key, 1)
+		return b != 0, rest, err
+	case Int8Type:
+		i, rest, err := data.ReadKeyInt(key, 1)
+		return int8(i), rest, err
+	case Int16Type:
+		i, rest, err := data.ReadKeyInt(key, 2)
+		return int16(i), rest, err
+	case Int32Type:
+		i, rest, err := data.ReadKeyInt(key, 4)
+		return int32(i), rest, err
+	case Int64Type:
+		return data.ReadKeyInt(key, 8)
+	case Uint8Type:
+		u, rest, err := data.ReadKeyUint(key, 1)
+		return uint8(u), rest, err
+	case Uint16Type:
+		u, rest, err := data.ReadKeyUint(key, 2)
+		return uint16(u), rest, err
+	case Uint32Type:
+		u, rest, err := data.ReadKeyUint(key, 4)
+		return uint32(u), rest, err
+	case Uint64Type:
+		return data.ReadKeyUint(key, 8)
+	case Float32Type:
+		return data.ReadKeyFloat32(key)
+	case Float64Type:
+		return data.ReadKeyFloat64(key)
+	case EnumType:
+		ordinal, rest, err := data.ReadKeyUint(key, 2)
+		if err != nil {
+			return nil, nil, err
+		}
+		if int(ordinal) >= len(f.Labels) {
+			return nil, nil, fmt.Errorf("%w: ordinal %d", ErrUnknownEnumLabel, ordinal)
+		}
+		return f.Labels[ordinal], rest, nil
+	case StringType:
+		b, rest, err := data.ReadKeyBytes(key)
+		return string(b), rest, err
+	case BytesType:
+		return data.ReadKeyBytes(key)
+	case UUIDType:
+		var uuid UUID
+		if len(key) < len(uuid) {
+			return nil, nil, data.ErrInvalidKey
+		}
+		copy(uuid[:], key)
+		return uuid, key[len(uuid):], nil
+	case TimeType:
+		nanoseconds, rest, err := data.ReadKeyInt(key, 8)
+		return TimeOfDay(nanoseconds), rest, err
+	case DecimalType:
+		high, rest, err := data.ReadKeyUint(key, 8)
+		if err != nil {
+			return nil, nil, err
+		}
+		low, rest, err := data.ReadKeyUint(rest, 8)
+		if err != nil {
+			return nil, nil, err
+		}
+		decimal, err := keyDecimal(high, low)
+		return decimal, rest, err
+	case DateType:
+		days, rest, err := data.ReadKeyInt(key, 4)
+		return daysDate(int32(days)), rest, err
+	case TimestampType, TimestampTzType:
+		nanoseconds, rest, err := data.ReadKeyInt(key, 8)
+		return time.Unix(0, nanoseconds).UTC(), rest, err
+	case DatetimeType:
+		unixEpoch, rest, err := data.ReadKeyInt(key, 8)
+		return time.Unix(unixEpoch, 0), rest, err
+	default:
+		return nil, nil, ErrUnknownFieldType
+	}
+}
+
+// decimalKey returns the 128 bits two's complement of the decimal coefficient at MaxDecimalScale,
+// sign bit flipped, which cannot overflow since 10^18 * 2^63 < 2^127.
+func decimalKey(d Decimal) (uint64, uint64) {
+	magnitude := uint64(d.Coefficient)
+	if d.Coefficient < 0 {
+		magnitude = -magnitude
+	}
+	high, low := bits.Mul64(magnitude, uint64(math.Pow10(MaxDecimalScale-int(d.Scale))))
+	if d.Coefficient < 0 {
+		high, low = negate128(high, low)
+	}
+	return high ^ 1<<63, low
+}
+
+// keyDecimal reverses decimalKey, removing trailing zeros from the fraction.
+func keyDecimal(high uint64, low uint64) (Decimal, error) {
+	high ^= 1 << 63
+	negative := high&(1<<63) != 0
+	if negative {
+		high, low = negate128(high, low)
+	}
+
+	scale := uint8(MaxDecimalScale)
+	for scale > 0 {
+		quotientHigh, remainderHigh := high/10, high%10
+		quotientLow, remainder := bits.Div64(remainderHigh, low, 10)
+		if remainder != 0 {
+			break
+		}
+		high, low = quotientHigh, quotientLow
+		scale--
+	}
+
+	if high != 0 || low > math.MaxInt64 && !(negative && low == 1<<63) {
+		return Decimal{}, data.ErrInvalidKey
+	}
+	coefficient := int64(low)
+	if negative {
+		coefficient = int64(-low)
+	}
+	return Decimal{Coefficient: coefficient, Scale: scale}, nil
+}
+
+func negate128(high uint64, low uint64) (uint64, uint64) {
+	low, borrow := bits.Sub64(0, low, 0)
+	high, _ = bits.Sub64(0, high, borrow)
+	return high, low
+}
@@ -2,44 +2,68 @@ package catalog
 
 import (
 	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/tinydb/data"
 )
 
+const (
+	TupleHeaderSize = 2 // Layout version (uint16)
+)
+
 var (
+	ErrEmptyLayout      = errors.New("layout requires at least one field")
 	ErrFieldNotFound    = errors.New("field not found")
 	ErrUnknownFieldType = errors.New("unknown field type")
 	ErrWrongFieldType   = errors.New("wrong field type")
 	ErrNotNullable      = errors.New("cannot set field null status since it isn't nullable")
+	ErrInvalidEnum      = errors.New("enum field requires unique, non empty labels")
+	ErrUnknownEnumLabel = errors.New("unknown enum label")
 )
 
 type Layout struct {
-	Fields []Field
+	Fields  []Field
+	Version uint16 // Relation layout version, stored in each tuple header
+
+	fixedSize uint16 // Size of the fixed section, variable length values are stored after it
 }
 
 // Layout rules:
+//  0. Tuples start with a header holding the layout version.
 //  1. All null bitsets are placed at the beginning.
 //  2. Boolean fields are packed into byte, they can be packed along with nullable info bits.
 //  3. Variable fields are represented with fixed size values first: metadata.
 //     They are stored at the end to allow fast lookup for offset management.
 //     Variable section of variable fields are stored after all fixed size items, pointed to by offset and length in metadata.
 func NewLayout(fields []Field) (Layout, error) {
-	layout := Layout{
-		Fields: make([]Field, len(fields)),
-	}
 	if len(fields) == 0 {
-		return layout, nil
+		return Layout{}, ErrEmptyLayout
+	}
+	layout := Layout{
+		Fields:    make([]Field, len(fields)),
+		fixedSize: TupleHeaderSize,
 	}
 
 	// Null bitsets management
-	var bitsetOffset uint16
+	var bitsetOffset uint16 = TupleHeaderSize
 	var bitsetIndex uint8
 	for i, f := range fields {
 		field := Field{
+			Id:       f.Id,
 			Name:     f.Name,
 			Type:     f.Type,
 			Nullable: f.Nullable,
+			Default:  f.Default,
+			Labels:   slices.Clone(f.Labels),
+		}
+		if field.Type == EnumType {
+			if err := validateLabels(field.Labels); err != nil {
+				return Layout{}, err
+			}
 		}
 		if field.Nullable {
 			field.nullOffset = bitsetOffset
@@ -64,23 +88,32 @@ func NewLayout(fields []Field) (Layout, error) {
 		offset = bitsetOffset + 1
 	}
 
-	newBitsetRequired := false
+	// Bools may only share the last null bitset if it has room left
+	newBitsetRequired := bitsetIndex == 0
 	varLenFields := []struct {
+		index int
 		field Field
 		size  uint16
 	}{}
 	for i, field := range layout.Fields {
 		info, found := TypesInfoMap[field.Type]
 		if !found {
-			return Layout{}, ErrUnknownFieldType
+			definition, found := LookupType(field.Type)
+			if !found {
+				return Layout{}, ErrUnknownFieldType
+			}
+			info = definition.Info
+			field.custom = &definition
 		}
 
 		if info.VariableLength {
 			// Store for special processing
 			varLenFields = append(varLenFields, struct {
+				index int
 				field Field
 				size  uint16
 			}{
+				index: i,
 				field: field,
 				size:  info.Size,
 			})
@@ -113,12 +146,13 @@ func NewLayout(fields []Field) (Layout, error) {
 	}
 
 	// Put all variable length metadata fields at the end, their variable length value will be stored after those
-	for i, varLenField := range varLenFields {
+	for _, varLenField := range varLenFields {
 		varLenField.field.offset = offset
-		layout.Fields[i] = varLenField.field
+		layout.Fields[varLenField.index] = varLenField.field
 		offset += varLenField.size
 	}
 
+	layout.fixedSize = offset
 	return layout, nil
 }
 
@@ -131,16 +165,51 @@ func (l *Layout) GetField(name string) (Field, error) {
 	return Field{}, ErrFieldNotFound
 }
 
+// FixedSize returns the size of the tuples fixed section, variable length values being stored after it.
+func (l *Layout) FixedSize() uint16 {
+	return l.fixedSize
+}
+
+// ReadLayoutVersion returns the version of the layout a tuple was written with.
+func ReadLayoutVersion(tuple []byte) (uint16, error) {
+	return data.ReadUint16(tuple, 0)
+}
+
 type Field struct {
+	Id       uint16 // Stable column identifier across layout versions, assigned by the catalog
 	Name     string
 	Type     FieldType
 	Nullable bool
+	Default  any      // Value of the column in tuples written before it was added, nil being null
+	Labels   []string // Enum labels, tuples store the label ordinal
 
 	offset     uint16
 	packed     bool
-	packIndex  uint8  // In the case of packed value, identifies the bit index to look at
-	nullOffset uint16 // Where to find null info storing bitset
-	nullIndex  uint8  // At which position to look in the null info bitset
+	packIndex  uint8           // In the case of packed value, identifies the bit index to look at
+	nullOffset uint16          // Where to find null info storing bitset
+	nullIndex  uint8           // At which position to look in the null info bitset
+	custom     *TypeDefinition // Definition of a registered type, nil for built-in types
+}
+
+// Offset returns the position of the field value, or of the bitset holding it if the field is packed.
+func (f Field) Offset() uint16 {
+	return f.offset
+}
+
+func (f Field) Packed() bool {
+	return f.packed
+}
+
+func (f Field) PackIndex() uint8 {
+	return f.packIndex
+}
+
+func (f Field) NullOffset() uint16 {
+	return f.nullOffset
+}
+
+func (f Field) NullIndex() uint8 {
+	return f.nullIndex
 }
 
 func (f Field) IsNull(buffer []byte) (bool, error) {
@@ -169,6 +238,9 @@ func (f Field) Read(buffer []byte) (any, error) {
 	if f.packed {
 		return data.IsBitSet(buffer, f.offset, f.packIndex)
 	}
+	if f.custom != nil {
+		return f.readCustom(*f.custom, buffer)
+	}
 
 	switch f.Type {
 	case Int8Type:
@@ -183,6 +255,23 @@ func (f Field) Read(buffer []byte) (any, error) {
 		return data.ReadInt32(buffer, f.offset)
 	case Int64Type:
 		return data.ReadInt64(buffer, f.offset)
+	case Uint8Type:
+		return data.ReadByte(buffer, f.offset)
+	case Uint16Type:
+		return data.ReadUint16(buffer, f.offset)
+	case Uint32Type:
+		return data.ReadUint32(buffer, f.offset)
+	case Uint64Type:
+		return data.ReadUint64(buffer, f.offset)
+	case EnumType:
+		ordinal, err := data.ReadUint16(buffer, f.offset)
+		if err != nil {
+			return nil, err
+		}
+		if int(ordinal) >= len(f.Labels) {
+			return nil, fmt.Errorf("%w: ordinal %d", ErrUnknownEnumLabel, ordinal)
+		}
+		return f.Labels[ordinal], nil
 	case Float32Type:
 		return data.ReadFloat32(buffer, f.offset)
 	case Float64Type:
@@ -193,8 +282,52 @@ func (f Field) Read(buffer []byte) (any, error) {
 			return nil, err
 		}
 		return time.Unix(unixEpoch, 0), nil
-	case StringType:
+	case StringType, BytesType:
 		return readString(buffer, f.offset)
+	case DecimalType:
+		coefficient, err := data.ReadInt64(buffer, f.offset)
+		if err != nil {
+			return nil, err
+		}
+		scale, err := data.ReadByte(buffer, f.offset+8)
+		if err != nil {
+			return nil, err
+		}
+		return Decimal{Coefficient: coefficient, Scale: scale}, nil
+	case UUIDType:
+		bytes, err := data.ReadBytes(buffer, f.offset, uint16(len(UUID{})))
+		if err != nil {
+			return nil, err
+		}
+		return UUID(bytes), nil
+	case DateType:
+		days, err := data.ReadInt32(buffer, f.offset)
+		if err != nil {
+			return nil, err
+		}
+		return daysDate(days), nil
+	case TimeType:
+		nanoseconds, err := data.ReadInt64(buffer, f.offset)
+		if err != nil {
+			return nil, err
+		}
+		return TimeOfDay(nanoseconds), nil
+	case TimestampType:
+		nanoseconds, err := data.ReadInt64(buffer, f.offset)
+		if err != nil {
+			return nil, err
+		}
+		return time.Unix(0, nanoseconds).UTC(), nil
+	case TimestampTzType:
+		nanoseconds, err := data.ReadInt64(buffer, f.offset)
+		if err != nil {
+			return nil, err
+		}
+		offset, err := data.ReadInt32(buffer, f.offset+8)
+		if err != nil {
+			return nil, err
+		}
+		return time.Unix(0, nanoseconds).In(time.FixedZone("", int(offset))), nil
 	default:
 		return nil, ErrUnknownFieldType
 	}
@@ -208,6 +341,9 @@ func (f Field) Write(value any, buffer []byte) error {
 		}
 		return data.WriteBit(typedBool, buffer, f.offset, f.packIndex)
 	}
+	if f.custom != nil {
+		return f.writeCustom(*f.custom, value, buffer)
+	}
 
 	switch typedVal := value.(type) {
 	case int8:
@@ -218,15 +354,86 @@ func (f Field) Write(value any, buffer []byte) error {
 		return data.WriteInt32(typedVal, buffer, f.offset)
 	case int64:
 		return data.WriteInt64(typedVal, buffer, f.offset)
+	case uint8:
+		return data.WriteByte(typedVal, buffer, f.offset)
+	case uint16:
+		return data.WriteUint16(typedVal, buffer, f.offset)
+	case uint32:
+		return data.WriteUint32(typedVal, buffer, f.offset)
+	case uint64:
+		return data.WriteUint64(typedVal, buffer, f.offset)
+	case string:
+		if f.Type != EnumType {
+			return ErrUnknownFieldType
+		}
+		ordinal := slices.Index(f.Labels, typedVal)
+		if ordinal == -1 {
+			return fmt.Errorf("%w: %s", ErrUnknownEnumLabel, typedVal)
+		}
+		return data.WriteUint16(uint16(ordinal), buffer, f.offset)
 	case float32:
 		return data.WriteFloat32(typedVal, buffer, f.offset)
 	case float64:
 		return data.WriteFloat64(typedVal, buffer, f.offset)
 	case time.Time:
-		return data.WriteInt64(typedVal.Unix(), buffer, f.offset)
+		return f.writeTime(typedVal, buffer)
 	case WriteStringData:
 		return writeString(typedVal, buffer, f)
+	case Decimal:
+		if err := typedVal.validate(); err != nil {
+			return err
+		}
+		if err := data.WriteInt64(typedVal.Coefficient, buffer, f.offset); err != nil {
+			return err
+		}
+		return data.WriteByte(typedVal.Scale, buffer, f.offset+8)
+	case UUID:
+		return data.WriteBytes(typedVal[:], buffer, f.offset)
+	case TimeOfDay:
+		if err := typedVal.validate(); err != nil {
+			return err
+		}
+		return data.WriteInt64(int64(typedVal), buffer, f.offset)
 	default:
 		return ErrUnknownFieldType
 	}
 }
+
+func validateLabels(labels []string) error {
+	if len(labels) == 0 || len(labels) > math.MaxUint16+1 {
+		return ErrInvalidEnum
+	}
+	for i, label := range labels {
+		if label == "" || strings.Contains(label, enumLabelsSeparator) || slices.Contains(labels[:i], label) {
+			return ErrInvalidEnum
+		}
+	}
+	return nil
+}
+
+// writeTime writes a time value with the precision of the field type.
+func (f Field) writeTime(value time.Time, buffer []byte) error {
+	switch f.Type {
+	case DateType:
+		days, err := dateDays(value)
+		if err != nil {
+			return err
+		}
+		return data.WriteInt32(days, buffer, f.offset)
+	case TimestampType, TimestampTzType:
+		nanoseconds, err := timestampNanoseconds(value)
+		if err != nil {
+			return err
+		}
+		if err := data.WriteInt64(nanoseconds, buffer, f.offset); err != nil {
+			return err
+		}
+		if f.Type == TimestampType {
+			return nil
+		}
+		_, offset := value.Zone()
+		return data.WriteInt32(int32(offset), buffer, f.offset+8)
+	default:
+		return data.WriteInt64(value.Unix(), buffer, f.offset)
+	}
+}
@@ -0,0 +1,144 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/tinydb/data"
+)
+
+var (
+	ErrTypeAlreadyRegistered = errors.New("field type is already registered")
+	ErrInvalidTypeDefinition = errors.New("type definition requires an encoder, a decoder and a size for fixed size types")
+)
+
+// TypeDefinition describes a field type registered by an application.
+// Fixed size values are stored in the tuple fixed section; variable length values are stored
+// after it, like strings. Custom types cannot be packed.
+type TypeDefinition struct {
+	Info FieldTypeInfo
+
+	// Encode returns the encoded value, exactly Info.Size bytes long for fixed size types.
+	Encode func(value any) ([]byte, error)
+	// Decode reads an encoded value. The encoded slice must not be retained.
+	Decode func(encoded []byte) (any, error)
+	// Compare orders two encoded values, returning a negative number, zero or a positive number. Optional.
+	Compare func(a []byte, b []byte) int
+	// GoType is the type of decoded values, struct fields of that type being mapped to the registered type. Optional.
+	GoType reflect.Type
+}
+
+// typeRegistry holds the field types registered with RegisterType, built-in types excluded.
+type typeRegistry struct {
+	types   map[FieldType]TypeDefinition
+	goTypes map[reflect.Type]FieldType
+	mutex   *sync.RWMutex
+}
+
+var registry = typeRegistry{
+	types:   map[FieldType]TypeDefinition{},
+	goTypes: map[reflect.Type]FieldType{},
+	mutex:   &sync.RWMutex{},
+}
+
+// RegisterType makes a field type available to layouts, it is meant to be called at program initialization.
+// Registered types cannot be replaced, and a Go type can be the GoType of a single registered type.
+func RegisterType(fieldType FieldType, definition TypeDefinition) error {
+	if fieldType == "" || definition.Encode == nil || definition.Decode == nil || definition.Info.Packable ||
+		!definition.Info.VariableLength && definition.Info.Size == 0 {
+		return ErrInvalidTypeDefinition
+	}
+	if definition.Info.VariableLength {
+		// Stored like strings
+		definition.Info.Size = TypesInfoMap[StringType].Size
+	}
+
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	if _, found := TypesInfoMap[fieldType]; found {
+		return fmt.Errorf("%w: %s", ErrTypeAlreadyRegistered, fieldType)
+	}
+	if _, found := registry.types[fieldType]; found {
+		return fmt.Errorf("%w: %s", ErrTypeAlreadyRegistered, fieldType)
+	}
+	if definition.GoType != nil {
+		if registered, found := registry.goTypes[definition.GoType]; found {
+			return fmt.Errorf("%w: go type %s is mapped to %s", ErrTypeAlreadyRegistered, definition.GoType, registered)
+		}
+		registry.goTypes[definition.GoType] = fieldType
+	}
+	registry.types[fieldType] = definition
+	return nil
+}
+
+// TypeInfo returns the storage info of a built-in or registered field type.
+func TypeInfo(fieldType FieldType) (FieldTypeInfo, bool) {
+	if info, found := TypesInfoMap[fieldType]; found {
+		return info, true
+	}
+	definition, found := LookupType(fieldType)
+	return definition.Info, found
+}
+
+// LookupType returns the definition of a registered field type.
+func LookupType(fieldType FieldType) (TypeDefinition, bool) {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	definition, found := registry.types[fieldType]
+	return definition, found
+}
+
+// LookupGoType returns the registered field type having goType as GoType.
+func LookupGoType(goType reflect.Type) (FieldType, bool) {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	fieldType, found := registry.goTypes[goType]
+	return fieldType, found
+}
+
+// readCustom reads a value of a registered type, variable length values being stored like strings.
+func (f Field) readCustom(definition TypeDefinition, buffer []byte) (any, error) {
+	var encoded []byte
+	if definition.Info.VariableLength {
+		strData, err := readString(buffer, f.offset)
+		if err != nil {
+			return nil, err
+		}
+		if strData.Overflow.PageId != 0 {
+			return nil, fmt.Errorf("%w: field %s", ErrOverflowString, f.Name)
+		}
+		encoded = strData.StrBytes
+	} else {
+		if int(f.offset)+int(definition.Info.Size) > len(buffer) {
+			return nil, data.ErrOutOfBounds
+		}
+		encoded = buffer[f.offset : f.offset+definition.Info.Size]
+	}
+	return definition.Decode(encoded)
+}
+
+// writeCustom writes a value of a registered type.
+// Variable length values must be given as WriteStringData holding the encoded value, see Layout.EncodeRow.
+func (f Field) writeCustom(definition TypeDefinition, value any, buffer []byte) error {
+	if definition.Info.VariableLength {
+		strData, ok := value.(WriteStringData)
+		if !ok {
+			return ErrWrongFieldType
+		}
+		return writeString(strData, buffer, f)
+	}
+
+	encoded, err := definition.Encode(value)
+	if err != nil {
+		return err
+	}
+	if len(encoded) != int(definition.Info.Size) {
+		return fmt.Errorf("%w: encoded %s value is %d bytes long, expected %d", ErrInvalidValue, f.Type, len(encoded), definition.Info.Size)
+	}
+	return data.WriteBytes(encoded, buffer, f.offset)
+}
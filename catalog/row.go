@@ -0,0 +1,236 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/tinydb/data"
+	"github.com/tinydb/storage"
+)
+
+var (
+	ErrValuesCount           = errors.New("values count doesn't match layout fields count")
+	ErrLayoutVersionMismatch = errors.New("tuple was written with another layout version")
+	ErrOverflowString        = errors.New("string value is stored in overflow pages")
+)
+
+// EncodeRow builds a tuple from values given in layout fields order, nil values being null.
+// Strings, given as string or []byte, bytes and variable length registered types are stored
+// after the fixed section, without overflow.
+func (l *Layout) EncodeRow(values []any) ([]byte, error) {
+	if len(values) != len(l.Fields) {
+		return nil, fmt.Errorf("%w: expected %d values, got %d", ErrValuesCount, len(l.Fields), len(values))
+	}
+
+	size := int(l.fixedSize)
+	payloads := make([][]byte, len(values))
+	for i, field := range l.Fields {
+		if err := field.checkValue(values[i]); err != nil {
+			return nil, err
+		}
+		payload, err := field.variablePayload(values[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode field %s: %w", field.Name, err)
+		}
+		payloads[i] = payload
+		size += len(payload)
+	}
+	if size > storage.MaxTupleSize {
+		return nil, storage.ErrTupleTooLarge
+	}
+
+	tuple := make([]byte, size)
+	if err := data.WriteUint16(l.Version, tuple, 0); err != nil {
+		return nil, err
+	}
+
+	dataOffset := l.fixedSize
+	for i, field := range l.Fields {
+		value := values[i]
+		if value == nil {
+			if err := field.SetIsNull(true, tuple); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if field.variableLength() {
+			value = WriteStringData{
+				StrBytes:   payloads[i],
+				DataOffset: dataOffset,
+			}
+			dataOffset += uint16(len(payloads[i]))
+		}
+		if err := field.Write(value, tuple); err != nil {
+			return nil, fmt.Errorf("failed to write field %s: %w", field.Name, err)
+		}
+	}
+	return tuple, nil
+}
+
+// EncodeRowMap is like EncodeRow with values given by field name, missing values being null.
+func (l *Layout) EncodeRowMap(values map[string]any) ([]byte, error) {
+	ordered := make([]any, len(l.Fields))
+	found := 0
+	for i, field := range l.Fields {
+		if value, ok := values[field.Name]; ok {
+			ordered[i] = value
+			found++
+		}
+	}
+	if found != len(values) {
+		for name := range values {
+			if _, err := l.GetField(name); err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrFieldNotFound, name)
+			}
+		}
+	}
+	return l.EncodeRow(ordered)
+}
+
+// DecodeRow reads all values of a tuple, in layout fields order. Strings are returned as string, bytes as []byte.
+func (l *Layout) DecodeRow(tuple []byte) ([]any, error) {
+	version, err := ReadLayoutVersion(tuple)
+	if err != nil {
+		return nil, err
+	}
+	if version != l.Version {
+		return nil, fmt.Errorf("%w: expected %d, got %d", ErrLayoutVersionMismatch, l.Version, version)
+	}
+
+	values := make([]any, len(l.Fields))
+	for i, field := range l.Fields {
+		value, err := field.Read(tuple)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read field %s: %w", field.Name, err)
+		}
+		if strData, ok := value.(StringData); ok {
+			if strData.Overflow.PageId != 0 {
+				return nil, fmt.Errorf("%w: field %s", ErrOverflowString, field.Name)
+			}
+			if field.Type == BytesType {
+				value = strData.StrBytes
+			} else {
+				value = string(strData.StrBytes)
+			}
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// DecodeRowMap is like DecodeRow with values returned by field name.
+func (l *Layout) DecodeRowMap(tuple []byte) (map[string]any, error) {
+	values, err := l.DecodeRow(tuple)
+	if err != nil {
+		return nil, err
+	}
+
+	row := make(map[string]any, len(values))
+	for i, field := range l.Fields {
+		row[field.Name] = values[i]
+	}
+	return row, nil
+}
+
+// checkValue ensures a value can be written to the field, since Field.Write relies on the value type only.
+func (f Field) checkValue(value any) error {
+	if value == nil {
+		if !f.Nullable {
+			return fmt.Errorf("%w: %s", ErrNotNullable, f.Name)
+		}
+		return nil
+	}
+	if f.custom != nil {
+		// Checked by the type encoder
+		return nil
+	}
+
+	var ok bool
+	switch f.Type {
+	case BoolType:
+		_, ok = value.(bool)
+	case Int8Type:
+		_, ok = value.(int8)
+	case Int16Type:
+		_, ok = value.(int16)
+	case Int32Type:
+		_, ok = value.(int32)
+	case Int64Type:
+		_, ok = value.(int64)
+	case Float32Type:
+		_, ok = value.(float32)
+	case Float64Type:
+		_, ok = value.(float64)
+	case DatetimeType, DateType, TimestampType, TimestampTzType:
+		_, ok = value.(time.Time)
+	case StringType:
+		switch value.(type) {
+		case string, []byte:
+			ok = true
+		}
+	case BytesType:
+		_, ok = value.([]byte)
+	case Uint8Type:
+		_, ok = value.(uint8)
+	case Uint16Type:
+		_, ok = value.(uint16)
+	case Uint32Type:
+		_, ok = value.(uint32)
+	case Uint64Type:
+		_, ok = value.(uint64)
+	case EnumType:
+		var label string
+		label, ok = value.(string)
+		if ok && !slices.Contains(f.Labels, label) {
+			return fmt.Errorf("%w: %s given for field %s", ErrUnknownEnumLabel, label, f.Name)
+		}
+	case DecimalType:
+		_, ok = value.(Decimal)
+	case UUIDType:
+		_, ok = value.(UUID)
+	case TimeType:
+		_, ok = value.(TimeOfDay)
+	default:
+		return ErrUnknownFieldType
+	}
+	if !ok {
+		return fmt.Errorf("%w: %T given for field %s of type %s", ErrWrongFieldType, value, f.Name, f.Type)
+	}
+	return nil
+}
+
+func (f Field) variableLength() bool {
+	if f.custom != nil {
+		return f.custom.Info.VariableLength
+	}
+	return TypesInfoMap[f.Type].VariableLength
+}
+
+// variablePayload returns the payload of a variable length value, stored after the fixed section,
+// or nil for fixed size and null values.
+func (f Field) variablePayload(value any) ([]byte, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if f.custom != nil {
+		if !f.custom.Info.VariableLength {
+			return nil, nil
+		}
+		return f.custom.Encode(value)
+	}
+
+	switch typedVal := value.(type) {
+	case string:
+		if f.Type == EnumType {
+			return nil, nil
+		}
+		return []byte(typedVal), nil
+	case []byte:
+		return typedVal, nil
+	default:
+		return nil, nil
+	}
+}
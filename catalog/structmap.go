@@ -0,0 +1,382 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const StructTag = "tinydb"
+
+var (
+	ErrNotStruct         = errors.New("value isn't a struct or a pointer to a struct")
+	ErrUnsupportedGoType = errors.New("go type has no field type")
+	ErrStructMismatch    = errors.New("struct doesn't match layout")
+)
+
+var (
+	timeType  = reflect.TypeFor[time.Time]()
+	bytesType = reflect.TypeFor[[]byte]()
+
+	// Types of the values read and written by built-in fields, see goTypeOf
+	goTypes = map[FieldType]reflect.Type{
+		BoolType:        reflect.TypeFor[bool](),
+		Int8Type:        reflect.TypeFor[int8](),
+		Int16Type:       reflect.TypeFor[int16](),
+		Int32Type:       reflect.TypeFor[int32](),
+		Int64Type:       reflect.TypeFor[int64](),
+		Float32Type:     reflect.TypeFor[float32](),
+		Float64Type:     reflect.TypeFor[float64](),
+		DatetimeType:    timeType,
+		StringType:      reflect.TypeFor[string](),
+		BytesType:       bytesType,
+		DecimalType:     reflect.TypeFor[Decimal](),
+		UUIDType:        reflect.TypeFor[UUID](),
+		DateType:        timeType,
+		TimeType:        reflect.TypeFor[TimeOfDay](),
+		TimestampType:   timeType,
+		TimestampTzType: timeType,
+		Uint8Type:       reflect.TypeFor[uint8](),
+		Uint16Type:      reflect.TypeFor[uint16](),
+		Uint32Type:      reflect.TypeFor[uint32](),
+		Uint64Type:      reflect.TypeFor[uint64](),
+		EnumType:        reflect.TypeFor[string](),
+	}
+)
+
+// Struct fields are mapped to layout fields with the `tinydb:"name,nullable,type=datetime"` tag:
+//   - name defaults to the struct field name, "-" skips the struct field. Unexported fields are skipped.
+//   - the field type is derived from the struct field Go type, registered types being found by their GoType.
+//     time.Time is mapped to timestamp. The type option selects another field type of the same Go type,
+//     such as datetime, date or timestamptz for time.Time.
+//   - nullable marks the layout field as nullable. Pointer fields are always nullable, nil being null,
+//     while other nullable fields are stored as non null and read as their zero value when null.
+
+// structField is a struct field mapped to a layout field.
+type structField struct {
+	name      string
+	index     int // Struct field index
+	fieldType FieldType
+	nullable  bool
+}
+
+// StructMapper encodes and decodes values of a struct type to tuples of a layout.
+type StructMapper struct {
+	layout     Layout
+	structType reflect.Type
+	fields     []int // Struct field index of each layout field, -1 if the struct has none
+}
+
+// Mismatch describes a difference between a struct and a layout.
+type Mismatch struct {
+	Field  string
+	Reason string
+}
+
+func (m Mismatch) String() string {
+	return m.Field + ": " + m.Reason
+}
+
+// LayoutOf derives a layout from a struct, fields being in struct order.
+func LayoutOf(v any) (Layout, error) {
+	structType, err := structTypeOf(v)
+	if err != nil {
+		return Layout{}, err
+	}
+	structFields, err := parseStruct(structType)
+	if err != nil {
+		return Layout{}, err
+	}
+
+	fields := make([]Field, len(structFields))
+	for i, sf := range structFields {
+		fields[i] = Field{
+			Name:     sf.name,
+			Type:     sf.fieldType,
+			Nullable: sf.nullable,
+		}
+	}
+	return NewLayout(fields)
+}
+
+// CompareStruct reports the differences preventing a struct from being mapped to a layout:
+// layout fields missing from a struct, unless nullable, struct fields missing from the layout,
+// and fields having a different type or nullability.
+func CompareStruct(layout Layout, v any) ([]Mismatch, error) {
+	structType, err := structTypeOf(v)
+	if err != nil {
+		return nil, err
+	}
+	structFields, err := parseStruct(structType)
+	if err != nil {
+		return nil, err
+	}
+	_, mismatches := mapStruct(layout, structFields)
+	return mismatches, nil
+}
+
+// NewStructMapper maps a struct type to a layout, failing with ErrStructMismatch when they differ.
+func NewStructMapper(layout Layout, v any) (*StructMapper, error) {
+	structType, err := structTypeOf(v)
+	if err != nil {
+		return nil, err
+	}
+	structFields, err := parseStruct(structType)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, mismatches := mapStruct(layout, structFields)
+	if len(mismatches) != 0 {
+		reasons := make([]string, len(mismatches))
+		for i, mismatch := range mismatches {
+			reasons[i] = mismatch.String()
+		}
+		return nil, fmt.Errorf("%w: %s", ErrStructMismatch, strings.Join(reasons, ", "))
+	}
+
+	return &StructMapper{
+		layout:     layout,
+		structType: structType,
+		fields:     fields,
+	}, nil
+}
+
+// Encode builds a tuple from a struct or a pointer to a struct of the mapped type.
+func (m *StructMapper) Encode(v any) ([]byte, error) {
+	value := reflect.ValueOf(v)
+	if value.Kind() == reflect.Pointer {
+		value = value.Elem()
+	}
+	if value.Type() != m.structType {
+		return nil, fmt.Errorf("%w: expected %s, got %s", ErrWrongFieldType, m.structType, value.Type())
+	}
+
+	values := make([]any, len(m.layout.Fields))
+	for i, index := range m.fields {
+		if index == -1 {
+			continue
+		}
+		fieldValue := value.Field(index)
+		if fieldValue.Kind() == reflect.Pointer {
+			if fieldValue.IsNil() {
+				continue
+			}
+			fieldValue = fieldValue.Elem()
+		}
+		// Named types are converted to the type expected by the field
+		values[i] = fieldValue.Convert(goTypeOf(m.layout.Fields[i].Type)).Interface()
+	}
+	return m.layout.EncodeRow(values)
+}
+
+// Decode reads a tuple into a pointer to a struct of the mapped type.
+func (m *StructMapper) Decode(tuple []byte, v any) error {
+	pointer := reflect.ValueOf(v)
+	if pointer.Kind() != reflect.Pointer || pointer.Elem().Type() != m.structType {
+		return fmt.Errorf("%w: expected *%s, got %T", ErrWrongFieldType, m.structType, v)
+	}
+	value := pointer.Elem()
+
+	values, err := m.layout.DecodeRow(tuple)
+	if err != nil {
+		return err
+	}
+	for i, index := range m.fields {
+		if index == -1 {
+			continue
+		}
+		fieldValue := value.Field(index)
+		if values[i] == nil {
+			fieldValue.SetZero()
+			continue
+		}
+
+		decoded := reflect.ValueOf(values[i])
+		if fieldValue.Kind() == reflect.Pointer {
+			target := reflect.New(fieldValue.Type().Elem())
+			target.Elem().Set(decoded.Convert(target.Elem().Type()))
+			fieldValue.Set(target)
+		} else {
+			fieldValue.Set(decoded.Convert(fieldValue.Type()))
+		}
+	}
+	return nil
+}
+
+// ParseStructTag reads a struct field tinydb tag. The name and the field type are empty when the tag doesn't set them.
+func ParseStructTag(tag string) (name string, fieldType FieldType, nullable bool, skip bool) {
+	if tag == "-" {
+		return "", "", false, true
+	}
+	name, options, _ := strings.Cut(tag, ",")
+	for _, option := range strings.Split(options, ",") {
+		if option == "nullable" {
+			nullable = true
+		} else if value, found := strings.CutPrefix(option, "type="); found {
+			fieldType = FieldType(value)
+		}
+	}
+	return name, fieldType, nullable, false
+}
+
+func structTypeOf(v any) (reflect.Type, error) {
+	structType := reflect.TypeOf(v)
+	if structType != nil && structType.Kind() == reflect.Pointer {
+		structType = structType.Elem()
+	}
+	if structType == nil || structType.Kind() != reflect.Struct {
+		return nil, ErrNotStruct
+	}
+	return structType, nil
+}
+
+func parseStruct(structType reflect.Type) ([]structField, error) {
+	var fields []structField
+	for i := range structType.NumField() {
+		goField := structType.Field(i)
+		if !goField.IsExported() {
+			continue
+		}
+		name, tagType, nullable, skip := ParseStructTag(goField.Tag.Get(StructTag))
+		if skip {
+			continue
+		}
+		if name == "" {
+			name = goField.Name
+		}
+		sf := structField{
+			name:     name,
+			index:    i,
+			nullable: nullable,
+		}
+
+		goType := goField.Type
+		if goType.Kind() == reflect.Pointer {
+			sf.nullable = true
+			goType = goType.Elem()
+		}
+		fieldType, found := fieldTypeOf(goType)
+		if !found {
+			return nil, fmt.Errorf("%w: field %s of type %s", ErrUnsupportedGoType, goField.Name, goField.Type)
+		}
+		if tagType != "" {
+			if goTypeOf(tagType) != goTypeOf(fieldType) {
+				return nil, fmt.Errorf("%w: field %s of type %s cannot be stored as %s", ErrUnsupportedGoType, goField.Name, goField.Type, tagType)
+			}
+			fieldType = tagType
+		}
+		sf.fieldType = fieldType
+		fields = append(fields, sf)
+	}
+	return fields, nil
+}
+
+// fieldTypeOf returns the field type of a Go type, registered types taking precedence over built-in ones.
+func fieldTypeOf(goType reflect.Type) (FieldType, bool) {
+	if fieldType, found := LookupGoType(goType); found {
+		return fieldType, true
+	}
+
+	switch {
+	case goType == timeType:
+		return TimestampType, true
+	case goType == goTypes[DecimalType]:
+		return DecimalType, true
+	case goType == goTypes[UUIDType]:
+		return UUIDType, true
+	case goType == goTypes[TimeType]:
+		return TimeType, true
+	case goType.ConvertibleTo(bytesType) && goType.Kind() == reflect.Slice:
+		return BytesType, true
+	}
+
+	switch goType.Kind() {
+	case reflect.Bool:
+		return BoolType, true
+	case reflect.Int8:
+		return Int8Type, true
+	case reflect.Int16:
+		return Int16Type, true
+	case reflect.Int32:
+		return Int32Type, true
+	case reflect.Int64:
+		return Int64Type, true
+	case reflect.Uint8:
+		return Uint8Type, true
+	case reflect.Uint16:
+		return Uint16Type, true
+	case reflect.Uint32:
+		return Uint32Type, true
+	case reflect.Uint64:
+		return Uint64Type, true
+	case reflect.Float32:
+		return Float32Type, true
+	case reflect.Float64:
+		return Float64Type, true
+	case reflect.String:
+		return StringType, true
+	default:
+		return "", false
+	}
+}
+
+// goTypeOf returns the type of the values of a built-in or registered field type, nil if it has none.
+func goTypeOf(fieldType FieldType) reflect.Type {
+	if goType, found := goTypes[fieldType]; found {
+		return goType
+	}
+	definition, _ := LookupType(fieldType)
+	return definition.GoType
+}
+
+// mapStruct returns the struct field index of each layout field along with the differences between them.
+func mapStruct(layout Layout, structFields []structField) ([]int, []Mismatch) {
+	var mismatches []Mismatch
+	fields := make([]int, len(layout.Fields))
+	mapped := make([]bool, len(structFields))
+	for i, field := range layout.Fields {
+		fields[i] = -1
+		for j, sf := range structFields {
+			if sf.name != field.Name {
+				continue
+			}
+			mapped[j] = true
+			fields[i] = sf.index
+
+			// Types sharing the same Go type, such as datetime and date, are compatible
+			if sf.fieldType != field.Type && goTypeOf(sf.fieldType) != goTypeOf(field.Type) {
+				mismatches = append(mismatches, Mismatch{
+					Field:  field.Name,
+					Reason: fmt.Sprintf("struct field is %s, layout field is %s", sf.fieldType, field.Type),
+				})
+			}
+			if sf.nullable != field.Nullable {
+				mismatches = append(mismatches, Mismatch{
+					Field:  field.Name,
+					Reason: fmt.Sprintf("struct field nullable is %t, layout field nullable is %t", sf.nullable, field.Nullable),
+				})
+			}
+			break
+		}
+
+		if fields[i] == -1 && !field.Nullable {
+			mismatches = append(mismatches, Mismatch{
+				Field:  field.Name,
+				Reason: "non nullable layout field is missing from struct",
+			})
+		}
+	}
+
+	for j, sf := range structFields {
+		if !mapped[j] {
+			mismatches = append(mismatches, Mismatch{
+				Field:  sf.name,
+				Reason: "struct field is missing from layout",
+			})
+		}
+	}
+	return fields, mismatches
+}
@@ -0,0 +1,429 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/tinydb/heap"
+)
+
+// System relations store the catalog using the same tuple encoding as user relations:
+//
+//	tinydb_tables:  name, version
+//	tinydb_columns: table, version, position, id, name, type, nullable, default, labels
+//	tinydb_indexes: table, name, columns, unique
+//
+// Columns are stored for each layout version, version being the current layout version of the table.
+// A relation exists once its tinydb_tables tuple is stored, and a layout version once the table
+// version is updated. Columns are written before those and removed after them, each step being flushed,
+// so that a DDL interrupted by a crash either completes or leaves orphan tuples which are deleted at load time.
+const (
+	TablesRelation  = "tinydb_tables"
+	ColumnsRelation = "tinydb_columns"
+	IndexesRelation = "tinydb_indexes"
+
+	indexColumnsSeparator = "\x1f"
+	enumLabelsSeparator   = "\x1f"
+)
+
+var (
+	systemRelations = []string{TablesRelation, ColumnsRelation, IndexesRelation}
+
+	tablesLayout = mustNewLayout([]Field{
+		{Name: "name", Type: StringType},
+		{Name: "version", Type: Int32Type},
+	})
+	columnsLayout = mustNewLayout([]Field{
+		{Name: "table", Type: StringType},
+		{Name: "version", Type: Int32Type},
+		{Name: "position", Type: Int16Type},
+		{Name: "id", Type: Int32Type},
+		{Name: "name", Type: StringType},
+		{Name: "type", Type: StringType},
+		{Name: "nullable", Type: BoolType},
+		{Name: "default", Type: StringType, Nullable: true}, // Tuple encoded with a layout made of the column only
+		{Name: "labels", Type: StringType, Nullable: true},  // Enum labels
+	})
+	indexesLayout = mustNewLayout([]Field{
+		{Name: "table", Type: StringType},
+		{Name: "name", Type: StringType},
+		{Name: "columns", Type: StringType},
+		{Name: "unique", Type: BoolType},
+	})
+)
+
+// IsSystemRelation reports whether the relation is one of the relations storing the catalog.
+func IsSystemRelation(relation string) bool {
+	return slices.Contains(systemRelations, relation)
+}
+
+func mustNewLayout(fields []Field) Layout {
+	layout, err := NewLayout(fields)
+	if err != nil {
+		panic(err)
+	}
+	return layout
+}
+
+// persistRelation stores the relation columns then the relation itself.
+// Stored tuples are deleted if a step fails, so that a later attempt doesn't load them.
+func (l *Catalog) persistRelation(ctx context.Context, relation string, layout Layout) (relationRecords, error) {
+	columnRecords, err := l.persistColumns(ctx, relation, layout)
+	if err != nil {
+		return relationRecords{}, err
+	}
+
+	recordId, err := l.insertSystemTuple(ctx, TablesRelation, tablesLayout, []any{relation, int32(layout.Version)})
+	if err != nil {
+		err = fmt.Errorf("failed to store relation: %w", err)
+		return relationRecords{}, errors.Join(err, l.deleteSystemTuples(ctx, ColumnsRelation, columnRecords))
+	}
+	if err := l.buffers.FlushRelation(TablesRelation); err != nil {
+		if deleteErr := l.deleteSystemTuples(ctx, TablesRelation, []heap.RecordId{recordId}); deleteErr != nil {
+			return relationRecords{}, errors.Join(err, deleteErr)
+		}
+		return relationRecords{}, errors.Join(err, l.deleteSystemTuples(ctx, ColumnsRelation, columnRecords))
+	}
+	return relationRecords{
+		table:   recordId,
+		columns: columnRecords,
+	}, nil
+}
+
+// persistLayoutVersion stores the columns of a new layout version then makes it the relation version.
+func (l *Catalog) persistLayoutVersion(ctx context.Context, relation string, records *relationRecords, layout Layout) error {
+	columnRecords, err := l.persistColumns(ctx, relation, layout)
+	if err != nil {
+		return err
+	}
+
+	// Same size tuple, updated in place
+	tuple, err := tablesLayout.EncodeRow([]any{relation, int32(layout.Version)})
+	if err != nil {
+		return err
+	}
+	if err := l.heap.Update(ctx, records.table, tuple); err != nil {
+		// Columns of a version that doesn't exist would be loaded along with the next attempt
+		err = fmt.Errorf("failed to update relation version: %w", err)
+		return errors.Join(err, l.deleteSystemTuples(ctx, ColumnsRelation, columnRecords))
+	}
+	if err := l.buffers.FlushRelation(TablesRelation); err != nil {
+		return err
+	}
+
+	records.columns = append(records.columns, columnRecords...)
+	return nil
+}
+
+// persistColumns stores the columns of a layout version. Defaults are all encoded first,
+// and the stored columns are deleted if one of them cannot be stored.
+func (l *Catalog) persistColumns(ctx context.Context, relation string, layout Layout) ([]heap.RecordId, error) {
+	defaults := make([]any, len(layout.Fields))
+	for i, field := range layout.Fields {
+		defaultValue, err := encodeDefault(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid default value of column %s: %w", field.Name, err)
+		}
+		defaults[i] = defaultValue
+	}
+
+	var records []heap.RecordId
+	for i, field := range layout.Fields {
+		var labels any
+		if field.Type == EnumType {
+			labels = strings.Join(field.Labels, enumLabelsSeparator)
+		}
+
+		values := []any{relation, int32(layout.Version), int16(i), int32(field.Id), field.Name, string(field.Type), field.Nullable, defaults[i], labels}
+		recordId, err := l.insertSystemTuple(ctx, ColumnsRelation, columnsLayout, values)
+		if err != nil {
+			err = fmt.Errorf("failed to store column %s: %w", field.Name, err)
+			return nil, errors.Join(err, l.deleteSystemTuples(ctx, ColumnsRelation, records))
+		}
+		records = append(records, recordId)
+	}
+	if err := l.buffers.FlushRelation(ColumnsRelation); err != nil {
+		return nil, errors.Join(err, l.deleteSystemTuples(ctx, ColumnsRelation, records))
+	}
+	return records, nil
+}
+
+// encodeDefault returns the default value of a field encoded as a tuple of the field only, or nil.
+func encodeDefault(field Field) (any, error) {
+	if field.Default == nil {
+		return nil, nil
+	}
+
+	layout, err := NewLayout([]Field{{Name: field.Name, Type: field.Type, Labels: field.Labels}})
+	if err != nil {
+		return nil, err
+	}
+	tuple, err := layout.EncodeRow([]any{field.Default})
+	if err != nil {
+		return nil, err
+	}
+	return string(tuple), nil
+}
+
+func decodeDefault(field Field, encoded any) (any, error) {
+	if encoded == nil {
+		return nil, nil
+	}
+
+	layout, err := NewLayout([]Field{{Name: field.Name, Type: field.Type, Labels: field.Labels}})
+	if err != nil {
+		return nil, err
+	}
+	values, err := layout.DecodeRow([]byte(encoded.(string)))
+	if err != nil {
+		return nil, err
+	}
+	return values[0], nil
+}
+
+// unpersistRelation removes the relation tuple then its columns and indexes.
+func (l *Catalog) unpersistRelation(ctx context.Context, records relationRecords) error {
+	if err := l.heap.Delete(ctx, records.table); err != nil {
+		return fmt.Errorf("failed to delete relation: %w", err)
+	}
+	if err := l.buffers.FlushRelation(TablesRelation); err != nil {
+		return err
+	}
+
+	for _, recordId := range records.columns {
+		if err := l.heap.Delete(ctx, recordId); err != nil {
+			return fmt.Errorf("failed to delete column: %w", err)
+		}
+	}
+	for _, recordId := range records.indexes {
+		if err := l.heap.Delete(ctx, recordId); err != nil {
+			return fmt.Errorf("failed to delete index: %w", err)
+		}
+	}
+	if err := l.buffers.FlushRelation(ColumnsRelation); err != nil {
+		return err
+	}
+	return l.buffers.FlushRelation(IndexesRelation)
+}
+
+func (l *Catalog) persistIndex(ctx context.Context, relation string, index Index) (heap.RecordId, error) {
+	columns := strings.Join(index.Columns, indexColumnsSeparator)
+	recordId, err := l.insertSystemTuple(ctx, IndexesRelation, indexesLayout, []any{relation, index.Name, columns, index.Unique})
+	if err != nil {
+		return heap.RecordId{}, fmt.Errorf("failed to store index: %w", err)
+	}
+	return recordId, l.buffers.FlushRelation(IndexesRelation)
+}
+
+// persistIndexChanges rewrites the tuples of the relation indexes whose definition changed.
+func (l *Catalog) persistIndexChanges(ctx context.Context, relation string, records *relationRecords, previous []Index, indexes []Index) error {
+	changed := false
+	for i, index := range indexes {
+		if index.Name == previous[i].Name && index.Unique == previous[i].Unique && slices.Equal(index.Columns, previous[i].Columns) {
+			continue
+		}
+		if !changed {
+			records.indexes = slices.Clone(records.indexes)
+			changed = true
+		}
+
+		columns := strings.Join(index.Columns, indexColumnsSeparator)
+		tuple, err := indexesLayout.EncodeRow([]any{relation, index.Name, columns, index.Unique})
+		if err != nil {
+			return err
+		}
+		recordId, err := l.heap.Rewrite(ctx, records.indexes[i], tuple)
+		if err != nil {
+			return fmt.Errorf("failed to store index %s: %w", index.Name, err)
+		}
+		records.indexes[i] = recordId
+	}
+	if !changed {
+		return nil
+	}
+	return l.buffers.FlushRelation(IndexesRelation)
+}
+
+// deleteSystemTuples removes tuples of a system relation, then flushes it.
+func (l *Catalog) deleteSystemTuples(ctx context.Context, relation string, records []heap.RecordId) error {
+	for _, recordId := range records {
+		if err := l.heap.Delete(ctx, recordId); err != nil {
+			return fmt.Errorf("failed to delete tuple %s of %s: %w", recordId, relation, err)
+		}
+	}
+	return l.buffers.FlushRelation(relation)
+}
+
+func (l *Catalog) insertSystemTuple(ctx context.Context, relation string, layout Layout, values []any) (heap.RecordId, error) {
+	tuple, err := layout.EncodeRow(values)
+	if err != nil {
+		return heap.RecordId{}, err
+	}
+	return l.heap.Insert(ctx, relation, tuple)
+}
+
+type columnRow struct {
+	recordId heap.RecordId
+	version  int32
+	position int16
+	field    Field
+}
+
+// load reads all system relations and builds the relations data.
+// Orphan tuples are deleted so that they cannot be mistaken for those of a relation or layout version created later.
+func (l *Catalog) load(ctx context.Context) error {
+	versions := map[string]int32{}
+	err := l.heap.Scan(ctx, TablesRelation, func(recordId heap.RecordId, tuple []byte) error {
+		values, err := tablesLayout.DecodeRow(tuple)
+		if err != nil {
+			return err
+		}
+		relation := values[0].(string)
+		versions[relation] = values[1].(int32)
+		l.relations[relation] = RelationData{
+			records: relationRecords{table: recordId},
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var orphanColumns, orphanIndexes []heap.RecordId
+	columns := map[string][]columnRow{}
+	err = l.heap.Scan(ctx, ColumnsRelation, func(recordId heap.RecordId, tuple []byte) error {
+		values, err := columnsLayout.DecodeRow(tuple)
+		if err != nil {
+			return err
+		}
+		relation := values[0].(string)
+		version, found := versions[relation]
+		if !found || values[1].(int32) > version {
+			orphanColumns = append(orphanColumns, recordId)
+			return nil
+		}
+
+		field := Field{
+			Id:       uint16(values[3].(int32)),
+			Name:     values[4].(string),
+			Type:     FieldType(values[5].(string)),
+			Nullable: values[6].(bool),
+		}
+		if labels, ok := values[8].(string); ok {
+			field.Labels = strings.Split(labels, enumLabelsSeparator)
+		}
+		field.Default, err = decodeDefault(field, values[7])
+		if err != nil {
+			return err
+		}
+		columns[relation] = append(columns[relation], columnRow{
+			recordId: recordId,
+			version:  values[1].(int32),
+			position: values[2].(int16),
+			field:    field,
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = l.heap.Scan(ctx, IndexesRelation, func(recordId heap.RecordId, tuple []byte) error {
+		values, err := indexesLayout.DecodeRow(tuple)
+		if err != nil {
+			return err
+		}
+		relation := values[0].(string)
+		relData, found := l.relations[relation]
+		if !found {
+			orphanIndexes = append(orphanIndexes, recordId)
+			return nil
+		}
+		relData.indexes = append(relData.indexes, Index{
+			Name:    values[1].(string),
+			Columns: strings.Split(values[2].(string), indexColumnsSeparator),
+			Unique:  values[3].(bool),
+		})
+		relData.records.indexes = append(relData.records.indexes, recordId)
+		l.relations[relation] = relData
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := l.deleteSystemTuples(ctx, ColumnsRelation, orphanColumns); err != nil {
+		return err
+	}
+	if err := l.deleteSystemTuples(ctx, IndexesRelation, orphanIndexes); err != nil {
+		return err
+	}
+
+	for relation, relData := range l.relations {
+		relColumns := columns[relation]
+		slices.SortFunc(relColumns, func(a, b columnRow) int {
+			if a.version != b.version {
+				return int(a.version - b.version)
+			}
+			return int(a.position) - int(b.position)
+		})
+
+		relData.layouts = make([]Layout, versions[relation]+1)
+		for version := range relData.layouts {
+			var fields []Field
+			for _, column := range relColumns {
+				if int(column.version) != version {
+					continue
+				}
+				if int(column.position) != len(fields) {
+					return fmt.Errorf("%w: relation %s column positions aren't contiguous", ErrSystemRelationCorrupted, relation)
+				}
+				fields = append(fields, column.field)
+			}
+			if len(fields) == 0 {
+				return fmt.Errorf("%w: relation %s layout version %d has no column", ErrSystemRelationCorrupted, relation, version)
+			}
+
+			layout, err := NewLayout(fields)
+			if err != nil {
+				return fmt.Errorf("invalid layout of relation %s: %w", relation, err)
+			}
+			layout.Version = uint16(version)
+			relData.layouts[version] = layout
+		}
+
+		for _, column := range relColumns {
+			relData.records.columns = append(relData.records.columns, column.recordId)
+		}
+		renameIndexColumns(relData)
+		l.relations[relation] = relData
+	}
+	return nil
+}
+
+// renameIndexColumns replaces index column names of past layout versions by the current field names,
+// for indexes whose definition wasn't rewritten after a field renaming because of a crash.
+func renameIndexColumns(relData RelationData) {
+	current := relData.layout()
+	for _, index := range relData.indexes {
+		for i, column := range index.Columns {
+			if _, err := current.GetField(column); err == nil {
+				continue
+			}
+			for version := len(relData.layouts) - 2; version >= 0; version-- {
+				past, err := relData.layouts[version].GetField(column)
+				if err != nil {
+					continue
+				}
+				j := slices.IndexFunc(current.Fields, func(f Field) bool { return f.Id == past.Id })
+				if j != -1 {
+					index.Columns[i] = current.Fields[j].Name
+				}
+				break
+			}
+		}
+	}
+}
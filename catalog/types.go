@@ -1,18 +1,31 @@
 package catalog
 
 const (
-	BoolType     FieldType = "bool"
-	Int8Type     FieldType = "int8"
-	Int16Type    FieldType = "int16"
-	Int32Type    FieldType = "int32"
-	Int64Type    FieldType = "int64"
-	Float32Type  FieldType = "float32"
-	Float64Type  FieldType = "float64"
-	DatetimeType FieldType = "datetime"
-	StringType   FieldType = "string"
+	BoolType        FieldType = "bool"
+	Int8Type        FieldType = "int8"
+	Int16Type       FieldType = "int16"
+	Int32Type       FieldType = "int32"
+	Int64Type       FieldType = "int64"
+	Float32Type     FieldType = "float32"
+	Float64Type     FieldType = "float64"
+	DatetimeType    FieldType = "datetime"
+	StringType      FieldType = "string"
+	BytesType       FieldType = "bytes"
+	DecimalType     FieldType = "decimal"
+	UUIDType        FieldType = "uuid"
+	DateType        FieldType = "date"
+	TimeType        FieldType = "time"
+	TimestampType   FieldType = "timestamp"
+	TimestampTzType FieldType = "timestamptz"
+	Uint8Type       FieldType = "uint8"
+	Uint16Type      FieldType = "uint16"
+	Uint32Type      FieldType = "uint32"
+	Uint64Type      FieldType = "uint64"
+	EnumType        FieldType = "enum"
 )
 
 var (
+	// Built-in types, registered types are found with TypeInfo
 	TypesInfoMap = map[FieldType]FieldTypeInfo{
 		BoolType: {
 			Packable: true,
@@ -44,6 +57,43 @@ var (
 			Size:           14,
 			VariableLength: true,
 		},
+		BytesType: {
+			Size:           14, // Same as string
+			VariableLength: true,
+		},
+		DecimalType: {
+			Size: 9, // Coefficient (int64) + scale (uint8)
+		},
+		UUIDType: {
+			Size: 16,
+		},
+		DateType: {
+			Size: 4, // Days since Unix epoch (int32)
+		},
+		TimeType: {
+			Size: 8, // Nanoseconds since midnight (int64)
+		},
+		TimestampType: {
+			Size: 8, // Nanoseconds since Unix epoch (int64)
+		},
+		TimestampTzType: {
+			Size: 12, // Nanoseconds since Unix epoch (int64) + UTC offset in seconds (int32)
+		},
+		Uint8Type: {
+			Size: 1,
+		},
+		Uint16Type: {
+			Size: 2,
+		},
+		Uint32Type: {
+			Size: 4,
+		},
+		Uint64Type: {
+			Size: 8,
+		},
+		EnumType: {
+			Size: 2, // Label ordinal (uint16), labels are stored in the catalog
+		},
 	}
 )
 
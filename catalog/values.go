@@ -0,0 +1,148 @@
+package catalog
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	MaxDecimalScale = 18
+	secondsPerDay   = 24 * 60 * 60
+)
+
+var (
+	ErrInvalidValue = errors.New("invalid value for field type")
+)
+
+var (
+	// Range of timestamps, stored as int64 nanoseconds
+	minTimestamp = time.Unix(0, math.MinInt64)
+	maxTimestamp = time.Unix(0, math.MaxInt64)
+)
+
+// Decimal is an exact decimal number: Coefficient * 10^-Scale.
+type Decimal struct {
+	Coefficient int64
+	Scale       uint8
+}
+
+// ParseDecimal reads a decimal written as an optionally signed sequence of digits with an optional decimal point.
+func ParseDecimal(s string) (Decimal, error) {
+	integer, fraction, _ := strings.Cut(s, ".")
+	if len(fraction) > MaxDecimalScale || strings.ContainsAny(fraction, "+-") || (integer == "" || integer == "-" || integer == "+") && fraction == "" {
+		return Decimal{}, fmt.Errorf("%w: decimal %q", ErrInvalidValue, s)
+	}
+	if integer == "" || integer == "-" || integer == "+" {
+		integer += "0"
+	}
+
+	coefficient, err := strconv.ParseInt(integer+fraction, 10, 64)
+	if err != nil {
+		return Decimal{}, fmt.Errorf("%w: decimal %q", ErrInvalidValue, s)
+	}
+	return Decimal{
+		Coefficient: coefficient,
+		Scale:       uint8(len(fraction)),
+	}, nil
+}
+
+func (d Decimal) String() string {
+	digits := strconv.FormatInt(d.Coefficient, 10)
+	if d.Scale == 0 {
+		return digits
+	}
+
+	sign := ""
+	if d.Coefficient < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	if len(digits) <= int(d.Scale) {
+		digits = strings.Repeat("0", int(d.Scale)-len(digits)+1) + digits
+	}
+	point := len(digits) - int(d.Scale)
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// Float64 returns the nearest float of the decimal.
+func (d Decimal) Float64() float64 {
+	return float64(d.Coefficient) / math.Pow10(int(d.Scale))
+}
+
+func (d Decimal) validate() error {
+	if d.Scale > MaxDecimalScale {
+		return fmt.Errorf("%w: decimal scale %d is greater than %d", ErrInvalidValue, d.Scale, MaxDecimalScale)
+	}
+	return nil
+}
+
+// UUID is a 16 bytes universally unique identifier.
+type UUID [16]byte
+
+// ParseUUID reads a UUID written as 32 hexadecimal digits, optionally in the 8-4-4-4-12 hyphenated form.
+func ParseUUID(s string) (UUID, error) {
+	if len(s) == 36 && s[8] == '-' && s[13] == '-' && s[18] == '-' && s[23] == '-' {
+		s = s[:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+	}
+
+	var uuid UUID
+	if len(s) != 2*len(uuid) {
+		return UUID{}, fmt.Errorf("%w: uuid %q", ErrInvalidValue, s)
+	}
+	if _, err := hex.Decode(uuid[:], []byte(s)); err != nil {
+		return UUID{}, fmt.Errorf("%w: uuid %q", ErrInvalidValue, s)
+	}
+	return uuid, nil
+}
+
+func (u UUID) String() string {
+	encoded := hex.EncodeToString(u[:])
+	return encoded[:8] + "-" + encoded[8:12] + "-" + encoded[12:16] + "-" + encoded[16:20] + "-" + encoded[20:]
+}
+
+// TimeOfDay is the time elapsed since midnight, less than 24 hours.
+type TimeOfDay time.Duration
+
+// TimeOfDayOf returns the time of day of t, in its location.
+func TimeOfDayOf(t time.Time) TimeOfDay {
+	hour, minute, second := t.Clock()
+	return TimeOfDay(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute +
+		time.Duration(second)*time.Second + time.Duration(t.Nanosecond()))
+}
+
+func (t TimeOfDay) String() string {
+	return time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(t)).Format("15:04:05.999999999")
+}
+
+func (t TimeOfDay) validate() error {
+	if t < 0 || time.Duration(t) >= 24*time.Hour {
+		return fmt.Errorf("%w: time of day %s out of range", ErrInvalidValue, time.Duration(t))
+	}
+	return nil
+}
+
+// dateDays returns the days since the Unix epoch of the date of t, in its location.
+func dateDays(t time.Time) (int32, error) {
+	year, month, day := t.Date()
+	days := time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Unix() / secondsPerDay
+	if days < math.MinInt32 || days > math.MaxInt32 {
+		return 0, fmt.Errorf("%w: date %s out of range", ErrInvalidValue, t.Format(time.DateOnly))
+	}
+	return int32(days), nil
+}
+
+// daysDate returns the UTC date of days since the Unix epoch.
+func daysDate(days int32) time.Time {
+	return time.Unix(int64(days)*secondsPerDay, 0).UTC()
+}
+
+func timestampNanoseconds(t time.Time) (int64, error) {
+	if t.Before(minTimestamp) || t.After(maxTimestamp) {
+		return 0, fmt.Errorf("%w: timestamp %s out of range", ErrInvalidValue, t.Format(time.RFC3339Nano))
+	}
+	return t.UnixNano(), nil
+}
@@ -0,0 +1,472 @@
+package main
+
+import (
+	"fmt"
+	"go/format"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tinydb/catalog"
+)
+
+// generator writes the source of a struct accessors.
+type generator struct {
+	strings.Builder
+	typeName string
+	layout   catalog.Layout
+	columns  []column
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(g, format, args...)
+}
+
+// generate returns the formatted source of the accessors of a struct type.
+func generate(pkg string, typeName string, layout catalog.Layout, columns []column) ([]byte, error) {
+	g := &generator{
+		typeName: typeName,
+		layout:   layout,
+		columns:  columns,
+	}
+	g.header(pkg)
+	g.registeredHelpers()
+	g.newTuple()
+	for _, col := range columns {
+		g.getter(col)
+	}
+	g.tupleSize()
+	g.encode()
+	g.decode()
+
+	source, err := format.Source([]byte(g.String()))
+	if err != nil {
+		return nil, fmt.Errorf("invalid generated source: %w", err)
+	}
+	return source, nil
+}
+
+func (g *generator) header(pkg string) {
+	hasType := func(fieldType catalog.FieldType) bool {
+		return slices.ContainsFunc(g.columns, func(col column) bool { return col.field.Type == fieldType })
+	}
+
+	g.printf("// Code generated by tinygen; DO NOT EDIT.\n\npackage %s\n\nimport (\n", pkg)
+	g.printf("\"encoding/binary\"\n\"fmt\"\n")
+	if hasType(catalog.Float32Type) || hasType(catalog.Float64Type) {
+		g.printf("\"math\"\n")
+	}
+	if slices.ContainsFunc(g.columns, func(col column) bool { return isTimeType(col.field.Type) }) {
+		g.printf("\"time\"\n")
+	}
+	g.printf("\n\"github.com/tinydb/catalog\"\n\"github.com/tinydb/data\"\n")
+	var imported []string
+	for _, col := range g.columns {
+		if col.imported == "" || slices.Contains(imported, col.imported) {
+			continue
+		}
+		imported = append(imported, col.imported)
+		if name, _, _ := strings.Cut(col.goType, "."); name != path.Base(col.imported) {
+			g.printf("%s %q\n", name, col.imported)
+		} else {
+			g.printf("%q\n", col.imported)
+		}
+	}
+	g.printf(")\n\n")
+
+	g.printf("// %sLayoutVersion is the layout version of %s tuples.\n", g.typeName, g.typeName)
+	g.printf("const %sLayoutVersion = %d\n\n", g.typeName, g.layout.Version)
+	g.printf("// %sTuple is a %s tuple validated by New%sTuple.\n", g.typeName, g.typeName, g.typeName)
+	g.printf("type %sTuple []byte\n\n", g.typeName)
+}
+
+// registeredHelpers writes the functions encoding and decoding values of registered types,
+// with the definitions registered when the accessors run.
+func (g *generator) registeredHelpers() {
+	if !slices.ContainsFunc(g.columns, isRegistered) {
+		return
+	}
+
+	g.printf("// lookup%sType returns the definition of a registered type, which must have the storage info it was generated with.\n", g.typeName)
+	g.printf("func lookup%sType(fieldType catalog.FieldType, info catalog.FieldTypeInfo) (catalog.TypeDefinition, error) {\n", g.typeName)
+	g.printf("definition, found := catalog.LookupType(fieldType)\nif !found {\n")
+	g.printf("return catalog.TypeDefinition{}, fmt.Errorf(\"%%w: %%s\", catalog.ErrUnknownFieldType, fieldType)\n}\n")
+	g.printf("if definition.Info != info {\n")
+	g.printf("return catalog.TypeDefinition{}, fmt.Errorf(\"%%w: %%s storage info differs from the generated one\", catalog.ErrInvalidTypeDefinition, fieldType)\n}\n")
+	g.printf("return definition, nil\n}\n\n")
+
+	g.printf("// encode%sValue encodes a value of a registered type.\n", g.typeName)
+	g.printf("func encode%sValue(fieldType catalog.FieldType, info catalog.FieldTypeInfo, value any) ([]byte, error) {\n", g.typeName)
+	g.printf("definition, err := lookup%sType(fieldType, info)\nif err != nil {\nreturn nil, err\n}\n", g.typeName)
+	g.printf("encoded, err := definition.Encode(value)\nif err != nil {\nreturn nil, err\n}\n")
+	g.printf("if !info.VariableLength && len(encoded) != int(info.Size) {\n")
+	g.printf("return nil, fmt.Errorf(\"%%w: encoded %%s value is %%d bytes long, expected %%d\", catalog.ErrInvalidValue, fieldType, len(encoded), info.Size)\n}\n")
+	g.printf("return encoded, nil\n}\n\n")
+
+	g.printf("// decode%sValue decodes a value of a registered type, encoded must not be retained.\n", g.typeName)
+	g.printf("func decode%sValue(fieldType catalog.FieldType, info catalog.FieldTypeInfo, encoded []byte) (any, error) {\n", g.typeName)
+	g.printf("definition, err := lookup%sType(fieldType, info)\nif err != nil {\nreturn nil, err\n}\n", g.typeName)
+	g.printf("return definition.Decode(encoded)\n}\n\n")
+}
+
+func (g *generator) newTuple() {
+	g.printf("// New%sTuple checks that tuple was written with the %s layout and that its values are within its bounds.\n", g.typeName, g.typeName)
+	g.printf("func New%sTuple(tuple []byte) (%sTuple, error) {\n", g.typeName, g.typeName)
+	g.printf("if len(tuple) < %d {\nreturn nil, data.ErrOutOfBounds\n}\n", g.layout.FixedSize())
+	g.printf("if version := binary.BigEndian.Uint16(tuple); version != %sLayoutVersion {\n", g.typeName)
+	g.printf("return nil, fmt.Errorf(\"%%w: expected %%d, got %%d\", catalog.ErrLayoutVersionMismatch, %sLayoutVersion, version)\n}\n", g.typeName)
+	for _, col := range g.columns {
+		if !isVariableLength(col) {
+			continue
+		}
+		offset := col.field.Offset()
+		if col.field.Nullable {
+			g.printf("if %s {\n", g.isNotNull("tuple", col))
+		}
+		g.printf("if int(binary.BigEndian.Uint16(tuple[%d:]))+int(binary.BigEndian.Uint16(tuple[%d:])) > len(tuple) {\n", offset, offset+2)
+		g.printf("return nil, data.ErrOutOfBounds\n}\n")
+		g.printf("if binary.BigEndian.Uint32(tuple[%d:]) != 0 {\n", offset+4)
+		g.printf("return nil, fmt.Errorf(\"%%w: field %%s\", catalog.ErrOverflowString, %q)\n}\n", col.field.Name)
+		if col.field.Nullable {
+			g.printf("}\n")
+		}
+	}
+	g.printf("return %sTuple(tuple), nil\n}\n\n", g.typeName)
+}
+
+func (g *generator) getter(col column) {
+	if isRegistered(col) {
+		g.registeredGetter(col)
+		return
+	}
+	goType, zero := valueType(col.field.Type), zeroValue(col.field.Type)
+
+	if col.field.Nullable {
+		g.printf("// %s returns the %s column value, and false if it is null.\n", col.goName, col.field.Name)
+		g.printf("func (t %sTuple) %s() (%s, bool) {\n", g.typeName, col.goName, goType)
+		g.printf("if %s {\nreturn %s, false\n}\n", g.isNull("t", col), zero)
+	} else {
+		g.printf("// %s returns the %s column value.\n", col.goName, col.field.Name)
+		g.printf("func (t %sTuple) %s() %s {\n", g.typeName, col.goName, goType)
+	}
+
+	value := readValue(col.field)
+	if isVariableLength(col) {
+		offset := col.field.Offset()
+		g.printf("start, length := binary.BigEndian.Uint16(t[%d:]), binary.BigEndian.Uint16(t[%d:])\n", offset, offset+2)
+		value = "t[start : start+length : start+length]"
+	}
+	if col.field.Nullable {
+		g.printf("return %s, true\n}\n\n", value)
+	} else {
+		g.printf("return %s\n}\n\n", value)
+	}
+}
+
+// registeredGetter writes the getter of a registered type column, decoding its value.
+func (g *generator) registeredGetter(col column) {
+	// Nullable getters also return whether the value isn't null
+	notNull, failed := "", ""
+	if col.field.Nullable {
+		notNull, failed = "true, ", "false, "
+		g.printf("// %s returns the %s column value, and false if it is null.\n", col.goName, col.field.Name)
+		g.printf("func (t %sTuple) %s() (value %s, notNull bool, err error) {\n", g.typeName, col.goName, col.goType)
+		g.printf("if %s {\nreturn value, false, nil\n}\n", g.isNull("t", col))
+	} else {
+		g.printf("// %s returns the %s column value.\n", col.goName, col.field.Name)
+		g.printf("func (t %sTuple) %s() (value %s, err error) {\n", g.typeName, col.goName, col.goType)
+	}
+
+	offset := col.field.Offset()
+	encoded := fmt.Sprintf("t[%d:%d]", offset, offset+typeInfo(col).Size)
+	if isVariableLength(col) {
+		g.printf("start, length := binary.BigEndian.Uint16(t[%d:]), binary.BigEndian.Uint16(t[%d:])\n", offset, offset+2)
+		encoded = "t[start : start+length : start+length]"
+	}
+	g.printf("decoded, err := decode%sValue(%q, %s, %s)\n", g.typeName, col.field.Type, infoLiteral(col), encoded)
+	g.printf("if err != nil {\nreturn value, %serr\n}\n", failed)
+	g.printf("value, ok := decoded.(%s)\nif !ok {\n", col.goType)
+	g.printf("return value, %sfmt.Errorf(\"%%w: %%s value decoded as %%T\", catalog.ErrWrongFieldType, %q, decoded)\n}\n", failed, col.field.Type)
+	g.printf("return value, %snil\n}\n\n", notNull)
+}
+
+func (g *generator) tupleSize() {
+	g.printf("// TupleSize returns the size of the tuple encoding v.\n")
+	if slices.ContainsFunc(g.columns, func(col column) bool { return isRegistered(col) && isVariableLength(col) }) {
+		g.printf("// Variable length values of registered types are encoded to be measured, a failing one being counted as empty.\n")
+	}
+	g.printf("func (v *%s) TupleSize() int {\nsize := %d\n", g.typeName, g.layout.FixedSize())
+	for _, col := range g.columns {
+		if !isVariableLength(col) {
+			continue
+		}
+		if isRegistered(col) {
+			value := "v." + col.goName
+			if col.pointer {
+				g.printf("if v.%s != nil {\n", col.goName)
+				value = "*" + value
+			}
+			g.printf("if encoded, err := encode%sValue(%q, %s, %s); err == nil {\nsize += len(encoded)\n}\n", g.typeName, col.field.Type, infoLiteral(col), value)
+			if col.pointer {
+				g.printf("}\n")
+			}
+			continue
+		}
+		if col.pointer {
+			g.printf("if v.%s != nil {\nsize += len(*v.%s)\n}\n", col.goName, col.goName)
+		} else {
+			g.printf("size += len(v.%s)\n", col.goName)
+		}
+	}
+	g.printf("return size\n}\n\n")
+}
+
+func (g *generator) encode() {
+	hasVariableLength := slices.ContainsFunc(g.columns, isVariableLength)
+
+	g.printf("// EncodeTuple writes v to the beginning of tuple and returns the written size.\n")
+	g.printf("// The tuple must be at least TupleSize bytes long.\n")
+	g.printf("func (v *%s) EncodeTuple(tuple []byte) (int, error) {\n", g.typeName)
+	g.printf("size := v.TupleSize()\nif len(tuple) < size || size > 0xFFFF {\nreturn 0, data.ErrOutOfBounds\n}\n")
+	g.printf("clear(tuple[:%d])\n", g.layout.FixedSize())
+	g.printf("binary.BigEndian.PutUint16(tuple, %sLayoutVersion)\n", g.typeName)
+	if hasVariableLength {
+		g.printf("dataOffset := %d\n", g.layout.FixedSize())
+	}
+
+	for _, col := range g.columns {
+		value := "v." + col.goName
+		if col.pointer {
+			g.printf("if v.%s == nil {\ntuple[%d] |= 1 << %d\n} else {\n", col.goName, col.field.NullOffset(), col.field.NullIndex())
+			value = "(*v." + col.goName + ")"
+		}
+		if isRegistered(col) {
+			g.writeRegistered(col, value)
+		} else {
+			g.writeValue(col.field, value)
+		}
+		if col.pointer {
+			g.printf("}\n")
+		}
+	}
+	g.printf("return size, nil\n}\n\n")
+}
+
+func (g *generator) writeValue(field catalog.Field, value string) {
+	offset := field.Offset()
+	switch field.Type {
+	case catalog.BoolType:
+		g.printf("if %s {\ntuple[%d] |= 1 << %d\n}\n", value, offset, field.PackIndex())
+	case catalog.Int8Type:
+		g.printf("tuple[%d] = byte(%s)\n", offset, value)
+	case catalog.Int16Type, catalog.Uint16Type:
+		g.printf("binary.BigEndian.PutUint16(tuple[%d:], uint16(%s))\n", offset, value)
+	case catalog.Int32Type, catalog.Uint32Type:
+		g.printf("binary.BigEndian.PutUint32(tuple[%d:], uint32(%s))\n", offset, value)
+	case catalog.Int64Type, catalog.Uint64Type:
+		g.printf("binary.BigEndian.PutUint64(tuple[%d:], uint64(%s))\n", offset, value)
+	case catalog.Uint8Type:
+		g.printf("tuple[%d] = %s\n", offset, value)
+	case catalog.Float32Type:
+		g.printf("binary.BigEndian.PutUint32(tuple[%d:], math.Float32bits(%s))\n", offset, value)
+	case catalog.Float64Type:
+		g.printf("binary.BigEndian.PutUint64(tuple[%d:], math.Float64bits(%s))\n", offset, value)
+	case catalog.DatetimeType:
+		g.printf("binary.BigEndian.PutUint64(tuple[%d:], uint64(%s.Unix()))\n", offset, value)
+	case catalog.DateType:
+		g.printf("{\nyear, month, day := %s.Date()\n", value)
+		g.printf("days := time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Unix() / %d\n", int64(24*time.Hour/time.Second))
+		g.printf("if int64(int32(days)) != days {\nreturn 0, catalog.ErrInvalidValue\n}\n")
+		g.printf("binary.BigEndian.PutUint32(tuple[%d:], uint32(days))\n}\n", offset)
+	case catalog.TimestampType, catalog.TimestampTzType:
+		// UnixNano overflows out of the timestamp range
+		g.printf("if !time.Unix(0, %s.UnixNano()).Equal(%s) {\nreturn 0, catalog.ErrInvalidValue\n}\n", value, value)
+		g.printf("binary.BigEndian.PutUint64(tuple[%d:], uint64(%s.UnixNano()))\n", offset, value)
+		if field.Type == catalog.TimestampTzType {
+			g.printf("{\n_, zoneOffset := %s.Zone()\nbinary.BigEndian.PutUint32(tuple[%d:], uint32(int32(zoneOffset)))\n}\n", value, offset+8)
+		}
+	case catalog.DecimalType:
+		g.printf("if %s.Scale > catalog.MaxDecimalScale {\nreturn 0, catalog.ErrInvalidValue\n}\n", value)
+		g.printf("binary.BigEndian.PutUint64(tuple[%d:], uint64(%s.Coefficient))\n", offset, value)
+		g.printf("tuple[%d] = %s.Scale\n", offset+8, value)
+	case catalog.UUIDType:
+		g.printf("copy(tuple[%d:], %s[:])\n", offset, value)
+	case catalog.TimeType:
+		g.printf("if %s < 0 || %s >= %d {\nreturn 0, catalog.ErrInvalidValue\n}\n", value, value, int64(24*time.Hour))
+		g.printf("binary.BigEndian.PutUint64(tuple[%d:], uint64(%s))\n", offset, value)
+	case catalog.StringType, catalog.BytesType:
+		g.printf("binary.BigEndian.PutUint16(tuple[%d:], uint16(dataOffset))\n", offset)
+		g.printf("binary.BigEndian.PutUint16(tuple[%d:], uint16(len(%s)))\n", offset+2, value)
+		g.printf("dataOffset += copy(tuple[dataOffset:], %s)\n", value)
+	}
+}
+
+// writeRegistered writes a value of a registered type, encoded with its definition.
+func (g *generator) writeRegistered(col column, value string) {
+	offset := col.field.Offset()
+	g.printf("if encoded, err := encode%sValue(%q, %s, %s); err != nil {\nreturn 0, err\n} else {\n", g.typeName, col.field.Type, infoLiteral(col), value)
+	if isVariableLength(col) {
+		// The size was computed by encoding the value a first time
+		g.printf("if dataOffset+len(encoded) > size {\nreturn 0, data.ErrOutOfBounds\n}\n")
+		g.printf("binary.BigEndian.PutUint16(tuple[%d:], uint16(dataOffset))\n", offset)
+		g.printf("binary.BigEndian.PutUint16(tuple[%d:], uint16(len(encoded)))\n", offset+2)
+		g.printf("dataOffset += copy(tuple[dataOffset:], encoded)\n")
+	} else {
+		g.printf("copy(tuple[%d:], encoded)\n", offset)
+	}
+	g.printf("}\n")
+}
+
+func (g *generator) decode() {
+	g.printf("// DecodeTuple reads a tuple into v. Null values of non pointer fields are read as zero values.\n")
+	g.printf("func (v *%s) DecodeTuple(tuple []byte) error {\n", g.typeName)
+	g.printf("t, err := New%sTuple(tuple)\nif err != nil {\nreturn err\n}\n", g.typeName)
+
+	for _, col := range g.columns {
+		// Strings are copied out of the tuple
+		convert := func(value string) string {
+			switch col.field.Type {
+			case catalog.StringType:
+				return "string(" + value + ")"
+			case catalog.BytesType:
+				return "append([]byte(nil), " + value + "...)"
+			default:
+				return value
+			}
+		}
+
+		switch {
+		case isRegistered(col) && col.pointer:
+			g.printf("if value, ok, err := t.%s(); err != nil {\nreturn err\n} else if ok {\n", col.goName)
+			g.printf("v.%s = &value\n} else {\nv.%s = nil\n}\n", col.goName, col.goName)
+		case isRegistered(col) && col.field.Nullable:
+			g.printf("if value, _, err := t.%s(); err != nil {\nreturn err\n} else {\nv.%s = value\n}\n", col.goName, col.goName)
+		case isRegistered(col):
+			g.printf("if value, err := t.%s(); err != nil {\nreturn err\n} else {\nv.%s = value\n}\n", col.goName, col.goName)
+		case col.pointer:
+			g.printf("if value, ok := t.%s(); ok {\n", col.goName)
+			g.printf("converted := %s\nv.%s = &converted\n", convert("value"), col.goName)
+			g.printf("} else {\nv.%s = nil\n}\n", col.goName)
+		case col.field.Nullable:
+			g.printf("{\nvalue, _ := t.%s()\nv.%s = %s\n}\n", col.goName, col.goName, convert("value"))
+		case col.field.Type == catalog.BytesType:
+			// Reuses the slice capacity
+			g.printf("v.%s = append(v.%s[:0], t.%s()...)\n", col.goName, col.goName, col.goName)
+		default:
+			g.printf("v.%s = %s\n", col.goName, convert("t."+col.goName+"()"))
+		}
+	}
+	g.printf("return nil\n}\n")
+}
+
+// isNull returns the condition of a nullable column value being null.
+func (g *generator) isNull(tuple string, col column) string {
+	return fmt.Sprintf("%s[%d]&(1<<%d) != 0", tuple, col.field.NullOffset(), col.field.NullIndex())
+}
+
+func (g *generator) isNotNull(tuple string, col column) string {
+	return fmt.Sprintf("%s[%d]&(1<<%d) == 0", tuple, col.field.NullOffset(), col.field.NullIndex())
+}
+
+// readValue returns the expression reading a fixed size value from tuple t.
+func readValue(field catalog.Field) string {
+	offset := field.Offset()
+	switch field.Type {
+	case catalog.BoolType:
+		return fmt.Sprintf("t[%d]&(1<<%d) != 0", offset, field.PackIndex())
+	case catalog.Int8Type:
+		return fmt.Sprintf("int8(t[%d])", offset)
+	case catalog.Int16Type:
+		return fmt.Sprintf("int16(binary.BigEndian.Uint16(t[%d:]))", offset)
+	case catalog.Int32Type:
+		return fmt.Sprintf("int32(binary.BigEndian.Uint32(t[%d:]))", offset)
+	case catalog.Int64Type:
+		return fmt.Sprintf("int64(binary.BigEndian.Uint64(t[%d:]))", offset)
+	case catalog.Uint8Type:
+		return fmt.Sprintf("t[%d]", offset)
+	case catalog.Uint16Type:
+		return fmt.Sprintf("binary.BigEndian.Uint16(t[%d:])", offset)
+	case catalog.Uint32Type:
+		return fmt.Sprintf("binary.BigEndian.Uint32(t[%d:])", offset)
+	case catalog.Uint64Type:
+		return fmt.Sprintf("binary.BigEndian.Uint64(t[%d:])", offset)
+	case catalog.Float32Type:
+		return fmt.Sprintf("math.Float32frombits(binary.BigEndian.Uint32(t[%d:]))", offset)
+	case catalog.Float64Type:
+		return fmt.Sprintf("math.Float64frombits(binary.BigEndian.Uint64(t[%d:]))", offset)
+	case catalog.DatetimeType:
+		return fmt.Sprintf("time.Unix(int64(binary.BigEndian.Uint64(t[%d:])), 0)", offset)
+	case catalog.DateType:
+		return fmt.Sprintf("time.Unix(int64(int32(binary.BigEndian.Uint32(t[%d:])))*%d, 0).UTC()", offset, int64(24*time.Hour/time.Second))
+	case catalog.TimestampType:
+		return fmt.Sprintf("time.Unix(0, int64(binary.BigEndian.Uint64(t[%d:]))).UTC()", offset)
+	case catalog.TimestampTzType:
+		return fmt.Sprintf("time.Unix(0, int64(binary.BigEndian.Uint64(t[%d:]))).In(time.FixedZone(\"\", int(int32(binary.BigEndian.Uint32(t[%d:])))))", offset, offset+8)
+	case catalog.DecimalType:
+		return fmt.Sprintf("catalog.Decimal{Coefficient: int64(binary.BigEndian.Uint64(t[%d:])), Scale: t[%d]}", offset, offset+8)
+	case catalog.UUIDType:
+		return fmt.Sprintf("catalog.UUID(t[%d:%d])", offset, offset+16)
+	case catalog.TimeType:
+		return fmt.Sprintf("catalog.TimeOfDay(binary.BigEndian.Uint64(t[%d:]))", offset)
+	default:
+		return ""
+	}
+}
+
+// valueType returns the Go type of column values, string values being returned as a slice of the tuple.
+func valueType(fieldType catalog.FieldType) string {
+	switch fieldType {
+	case catalog.DatetimeType, catalog.DateType, catalog.TimestampType, catalog.TimestampTzType:
+		return "time.Time"
+	case catalog.StringType, catalog.BytesType:
+		return "[]byte"
+	case catalog.DecimalType:
+		return "catalog.Decimal"
+	case catalog.UUIDType:
+		return "catalog.UUID"
+	case catalog.TimeType:
+		return "catalog.TimeOfDay"
+	default:
+		return string(fieldType)
+	}
+}
+
+func zeroValue(fieldType catalog.FieldType) string {
+	switch fieldType {
+	case catalog.BoolType:
+		return "false"
+	case catalog.DatetimeType, catalog.DateType, catalog.TimestampType, catalog.TimestampTzType:
+		return "time.Time{}"
+	case catalog.StringType, catalog.BytesType:
+		return "nil"
+	case catalog.DecimalType:
+		return "catalog.Decimal{}"
+	case catalog.UUIDType:
+		return "catalog.UUID{}"
+	default:
+		return "0"
+	}
+}
+
+func isVariableLength(col column) bool {
+	return typeInfo(col).VariableLength
+}
+
+func isRegistered(col column) bool {
+	return col.goType != ""
+}
+
+func typeInfo(col column) catalog.FieldTypeInfo {
+	info, _ := catalog.TypeInfo(col.field.Type)
+	return info
+}
+
+// infoLiteral returns the expression of a registered type storage info.
+func infoLiteral(col column) string {
+	info := typeInfo(col)
+	if info.VariableLength {
+		return fmt.Sprintf("catalog.FieldTypeInfo{Size: %d, VariableLength: true}", info.Size)
+	}
+	return fmt.Sprintf("catalog.FieldTypeInfo{Size: %d}", info.Size)
+}
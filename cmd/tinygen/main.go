@@ -0,0 +1,305 @@
+// Tinygen generates typed tuple accessors for a Go struct, following the catalog layout rules.
+//
+// Usage, in the file declaring the struct:
+//
+//	//go:generate go run github.com/tinydb/cmd/tinygen -type User
+//
+// Struct fields are mapped to columns with the same tags as catalog.StructMapper. Fields of registered types
+// are declared with -register, repeated for each type, giving their Go type, field type and size:
+//
+//	//go:generate go run github.com/tinydb/cmd/tinygen -type User -register geo.Point=point:16 -register geo.Shape=shape:variable
+//
+// For a User struct, it emits:
+//   - UserLayoutVersion, the layout version written in tuples headers.
+//   - User.TupleSize, User.EncodeTuple and User.DecodeTuple. Only decoding strings and pointer fields allocates.
+//   - UserTuple, a tuple validated by NewUserTuple, with a getter per column. String getters return
+//     a slice of the tuple, and nullable getters report whether the value isn't null. Getters of registered
+//     types decode values with the definition registered at run time, also returning an error.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/tinydb/catalog"
+)
+
+var (
+	errTypeNotFound      = errors.New("struct type not found")
+	errUnsupportedType   = errors.New("unsupported field type")
+	errInvalidRegistered = errors.New("registered type must be given as GoType=fieldType:size, size being a number or \"variable\"")
+)
+
+// column is a struct field mapped to a layout field.
+type column struct {
+	goName   string
+	goType   string // Go type expression of registered types values
+	imported string // Import path of a registered Go type declared in another package
+	pointer  bool   // Nil being null
+	field    catalog.Field
+}
+
+// registeredTypes maps Go type expressions to registered field types, set with -register.
+// They are registered in the generator with their storage info only, so that layouts can be computed.
+type registeredTypes map[string]catalog.FieldType
+
+func (r registeredTypes) String() string {
+	return fmt.Sprint(map[string]catalog.FieldType(r))
+}
+
+func (r registeredTypes) Set(value string) error {
+	goType, definition, found := strings.Cut(value, "=")
+	fieldType, size, sized := strings.Cut(definition, ":")
+	if !found || !sized || goType == "" {
+		return errInvalidRegistered
+	}
+
+	info := catalog.FieldTypeInfo{VariableLength: size == "variable"}
+	if !info.VariableLength {
+		parsed, err := strconv.ParseUint(size, 10, 16)
+		if err != nil {
+			return fmt.Errorf("%w: %w", errInvalidRegistered, err)
+		}
+		info.Size = uint16(parsed)
+	}
+	err := catalog.RegisterType(catalog.FieldType(fieldType), catalog.TypeDefinition{
+		Info:   info,
+		Encode: func(any) ([]byte, error) { return nil, errors.ErrUnsupported },
+		Decode: func([]byte) (any, error) { return nil, errors.ErrUnsupported },
+	})
+	if err != nil {
+		return err
+	}
+	r[goType] = catalog.FieldType(fieldType)
+	return nil
+}
+
+func main() {
+	typeName := flag.String("type", "", "struct type name, required")
+	input := flag.String("input", os.Getenv("GOFILE"), "file declaring the struct, defaults to $GOFILE")
+	output := flag.String("output", "", "output file, defaults to <type>_tinydb.go")
+	version := flag.Uint("version", 0, "layout version written in tuples")
+	registered := registeredTypes{}
+	flag.Var(registered, "register", "registered type of struct fields, as GoType=fieldType:size with size a number or \"variable\", repeatable")
+	flag.Parse()
+
+	log.SetFlags(0)
+	log.SetPrefix("tinygen: ")
+	if *typeName == "" || *input == "" || *version > 0xFFFF {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *output == "" {
+		*output = filepath.Join(filepath.Dir(*input), strings.ToLower(*typeName)+"_tinydb.go")
+	}
+
+	pkg, columns, err := parseStruct(*input, *typeName, registered)
+	if err != nil {
+		log.Fatal(err)
+	}
+	layout, err := newLayout(columns, uint16(*version))
+	if err != nil {
+		log.Fatal(err)
+	}
+	source, err := generate(pkg, *typeName, layout, columns)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*output, source, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// parseStruct returns the package name of a file and the columns of one of its struct types.
+func parseStruct(fpath string, typeName string, registered registeredTypes) (string, []column, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), fpath, nil, 0)
+	if err != nil {
+		return "", nil, err
+	}
+	imports := map[string]string{} // Import path by package name
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return "", nil, err
+		}
+		name := path.Base(importPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = importPath
+	}
+
+	var structType *ast.StructType
+	ast.Inspect(file, func(node ast.Node) bool {
+		if spec, ok := node.(*ast.TypeSpec); ok && spec.Name.Name == typeName {
+			structType, _ = spec.Type.(*ast.StructType)
+		}
+		return structType == nil
+	})
+	if structType == nil {
+		return "", nil, fmt.Errorf("%w: %s", errTypeNotFound, typeName)
+	}
+
+	var columns []column
+	for _, astField := range structType.Fields.List {
+		var tag string
+		if astField.Tag != nil {
+			unquoted, err := strconv.Unquote(astField.Tag.Value)
+			if err != nil {
+				return "", nil, err
+			}
+			tag = reflect.StructTag(unquoted).Get(catalog.StructTag)
+		}
+		name, tagType, nullable, skip := catalog.ParseStructTag(tag)
+		if skip {
+			continue
+		}
+
+		for _, ident := range astField.Names {
+			if !ident.IsExported() {
+				continue
+			}
+			col := column{
+				goName: ident.Name,
+				field: catalog.Field{
+					Name:     name,
+					Nullable: nullable,
+				},
+			}
+			if col.field.Name == "" {
+				col.field.Name = ident.Name
+			}
+
+			expr := astField.Type
+			if star, ok := expr.(*ast.StarExpr); ok {
+				col.pointer = true
+				col.field.Nullable = true
+				expr = star.X
+			}
+			if registeredType, found := registered[exprString(expr)]; found {
+				col.field.Type = registeredType
+				col.goType = exprString(expr)
+				if selector, ok := expr.(*ast.SelectorExpr); ok {
+					if pkg, ok := selector.X.(*ast.Ident); ok {
+						col.imported = imports[pkg.Name]
+					}
+				}
+			} else {
+				col.field.Type, err = fieldType(expr)
+				if err != nil {
+					return "", nil, fmt.Errorf("field %s: %w", ident.Name, err)
+				}
+			}
+
+			// Time values can be stored with any time type
+			if tagType != "" && tagType != col.field.Type {
+				if !isTimeType(col.field.Type) || !isTimeType(tagType) {
+					return "", nil, fmt.Errorf("field %s: %w: %s cannot be stored as %s", ident.Name, errUnsupportedType, exprString(expr), tagType)
+				}
+				col.field.Type = tagType
+			}
+			columns = append(columns, col)
+		}
+	}
+	return file.Name.Name, columns, nil
+}
+
+func fieldType(expr ast.Expr) (catalog.FieldType, error) {
+	switch typed := expr.(type) {
+	case *ast.Ident:
+		switch typed.Name {
+		case "bool":
+			return catalog.BoolType, nil
+		case "int8":
+			return catalog.Int8Type, nil
+		case "int16":
+			return catalog.Int16Type, nil
+		case "int32":
+			return catalog.Int32Type, nil
+		case "int64":
+			return catalog.Int64Type, nil
+		case "uint8", "byte":
+			return catalog.Uint8Type, nil
+		case "uint16":
+			return catalog.Uint16Type, nil
+		case "uint32":
+			return catalog.Uint32Type, nil
+		case "uint64":
+			return catalog.Uint64Type, nil
+		case "float32":
+			return catalog.Float32Type, nil
+		case "float64":
+			return catalog.Float64Type, nil
+		case "string":
+			return catalog.StringType, nil
+		}
+	case *ast.ArrayType:
+		if elem, ok := typed.Elt.(*ast.Ident); ok && typed.Len == nil && (elem.Name == "byte" || elem.Name == "uint8") {
+			return catalog.BytesType, nil
+		}
+	case *ast.SelectorExpr:
+		pkg, ok := typed.X.(*ast.Ident)
+		if !ok {
+			break
+		}
+		switch pkg.Name + "." + typed.Sel.Name {
+		case "time.Time":
+			return catalog.TimestampType, nil
+		case "catalog.Decimal":
+			return catalog.DecimalType, nil
+		case "catalog.UUID":
+			return catalog.UUIDType, nil
+		case "catalog.TimeOfDay":
+			return catalog.TimeType, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", errUnsupportedType, exprString(expr))
+}
+
+func isTimeType(fieldType catalog.FieldType) bool {
+	switch fieldType {
+	case catalog.DatetimeType, catalog.DateType, catalog.TimestampType, catalog.TimestampTzType:
+		return true
+	default:
+		return false
+	}
+}
+
+func exprString(expr ast.Expr) string {
+	var buffer bytes.Buffer
+	if err := format.Node(&buffer, token.NewFileSet(), expr); err != nil {
+		return fmt.Sprintf("%T", expr)
+	}
+	return buffer.String()
+}
+
+// newLayout computes the layout of the columns, updating their field with its position.
+func newLayout(columns []column, version uint16) (catalog.Layout, error) {
+	fields := make([]catalog.Field, len(columns))
+	for i, col := range columns {
+		fields[i] = col.field
+	}
+	layout, err := catalog.NewLayout(fields)
+	if err != nil {
+		return catalog.Layout{}, err
+	}
+	layout.Version = version
+
+	for i := range columns {
+		columns[i].field = layout.Fields[i]
+	}
+	return layout, nil
+}
@@ -15,13 +15,9 @@ func WriteBit(isSet bool, buffer []byte, offset uint16, index uint8) error {
 	}
 
 	if isSet {
-		if (bitset & (1 << index)) != 0 {
-			// Unset bit
-			bitset ^= 1 << index
-		}
-	} else {
-		// Set bit
 		bitset |= 1 << index
+	} else {
+		bitset &^= 1 << index
 	}
 
 	return WriteByte(bitset, buffer, offset)
@@ -0,0 +1,57 @@
+package data
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"math"
+)
+
+// Comparisons of big-endian encoded values of the same length,
+// returning a negative number, zero or a positive number.
+
+// CompareUnsigned compares unsigned integers of any size.
+func CompareUnsigned(a []byte, b []byte) int {
+	return bytes.Compare(a, b)
+}
+
+// CompareSigned compares two's complement integers of any size.
+func CompareSigned(a []byte, b []byte) int {
+	if len(a) == 0 || len(b) == 0 {
+		return cmp.Compare(len(a), len(b))
+	}
+	// Flipping the sign bit makes the unsigned order match the signed one
+	if c := cmp.Compare(a[0]^0x80, b[0]^0x80); c != 0 {
+		return c
+	}
+	return bytes.Compare(a[1:], b[1:])
+}
+
+// CompareFloat32 compares IEEE 754 floats, -0 being equal to +0. NaN is greater than any number and equal to NaN.
+func CompareFloat32(a []byte, b []byte) int {
+	return compareFloat(float64(math.Float32frombits(binary.BigEndian.Uint32(a))), float64(math.Float32frombits(binary.BigEndian.Uint32(b))))
+}
+
+// CompareFloat64 compares IEEE 754 floats like CompareFloat32.
+func CompareFloat64(a []byte, b []byte) int {
+	return compareFloat(math.Float64frombits(binary.BigEndian.Uint64(a)), math.Float64frombits(binary.BigEndian.Uint64(b)))
+}
+
+func compareFloat(a float64, b float64) int {
+	aNaN, bNaN := math.IsNaN(a), math.IsNaN(b)
+	switch {
+	case aNaN && bNaN:
+		return 0
+	case aNaN:
+		return 1
+	case bNaN:
+		return -1
+	default:
+		return cmp.Compare(a, b)
+	}
+}
+
+// CompareBits compares the bits at the same index of two bitsets, unset being lower.
+func CompareBits(a byte, b byte, index uint8) int {
+	return cmp.Compare(a>>index&1, b>>index&1)
+}
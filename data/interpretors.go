@@ -85,6 +85,21 @@ func WriteInt32(value int32, buffer []byte, offset uint16) error {
 	return nil
 }
 
+func ReadUint64(buffer []byte, offset uint16) (uint64, error) {
+	if int(offset)+7 >= len(buffer) {
+		return 0, ErrOutOfBounds
+	}
+	return binary.BigEndian.Uint64(buffer[offset : offset+8]), nil
+}
+
+func WriteUint64(value uint64, buffer []byte, offset uint16) error {
+	if int(offset)+7 >= len(buffer) {
+		return ErrOutOfBounds
+	}
+	binary.BigEndian.PutUint64(buffer[offset:offset+8], value)
+	return nil
+}
+
 func ReadInt64(buffer []byte, offset uint16) (int64, error) {
 	if int(offset)+7 >= len(buffer) {
 		return 0, ErrOutOfBounds
@@ -119,7 +134,7 @@ func ReadFloat64(buffer []byte, offset uint16) (float64, error) {
 	if int(offset)+7 >= len(buffer) {
 		return 0, ErrOutOfBounds
 	}
-	return math.Float64frombits(binary.BigEndian.Uint64(buffer[offset : offset+4])), nil
+	return math.Float64frombits(binary.BigEndian.Uint64(buffer[offset : offset+8])), nil
 }
 
 func WriteFloat64(value float64, buffer []byte, offset uint16) error {
@@ -0,0 +1,160 @@
+package data
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// Memcomparable key encoding: values appended to a key with these functions are ordered by
+// bytes.Compare like the values themselves. Fixed size values are big-endian, byte strings are
+// escaped and terminated so that a value is never a prefix of a greater one.
+
+const (
+	// Column markers, written before each value of a composite key
+	KeyNullFirst byte = 0x00
+	KeyNotNull   byte = 0x01
+	KeyNullLast  byte = 0x02
+
+	keyEscape     byte = 0x00
+	keyEscaped    byte = 0xFF // Follows an escaped 0x00 byte
+	keyTerminator byte = 0x01 // Follows a 0x00 byte to end a byte string
+)
+
+var (
+	ErrInvalidKey = errors.New("malformed key")
+)
+
+// AppendKeyUint appends the size lowest bytes of an unsigned integer, size being 1, 2, 4 or 8.
+func AppendKeyUint(key []byte, value uint64, size int) []byte {
+	var buffer [8]byte
+	binary.BigEndian.PutUint64(buffer[:], value)
+	return append(key, buffer[8-size:]...)
+}
+
+// ReadKeyUint reads an unsigned integer of size bytes, returning it along with the rest of the key.
+func ReadKeyUint(key []byte, size int) (uint64, []byte, error) {
+	if len(key) < size {
+		return 0, nil, ErrInvalidKey
+	}
+	var buffer [8]byte
+	copy(buffer[8-size:], key[:size])
+	return binary.BigEndian.Uint64(buffer[:]), key[size:], nil
+}
+
+// AppendKeyInt appends a signed integer of size bytes, its sign bit flipped.
+func AppendKeyInt(key []byte, value int64, size int) []byte {
+	return AppendKeyUint(key, uint64(value)^signBit(size), size)
+}
+
+func ReadKeyInt(key []byte, size int) (int64, []byte, error) {
+	u, rest, err := ReadKeyUint(key, size)
+	if err != nil {
+		return 0, nil, err
+	}
+	// Restore the sign bit and extend it
+	shift := 64 - 8*size
+	return int64((u^signBit(size))<<shift) >> shift, rest, nil
+}
+
+// AppendKeyFloat64 appends a float, -0 being encoded as +0 and NaN after +Inf.
+func AppendKeyFloat64(key []byte, value float64) []byte {
+	switch {
+	case math.IsNaN(value):
+		value = math.NaN()
+	case value == 0:
+		value = 0
+	}
+	return AppendKeyUint(key, orderedFloatBits(math.Float64bits(value), 64), 8)
+}
+
+func ReadKeyFloat64(key []byte) (float64, []byte, error) {
+	u, rest, err := ReadKeyUint(key, 8)
+	if err != nil {
+		return 0, nil, err
+	}
+	return math.Float64frombits(floatBits(u, 64)), rest, nil
+}
+
+// AppendKeyFloat32 appends a float, -0 being encoded as +0 and NaN after +Inf.
+func AppendKeyFloat32(key []byte, value float32) []byte {
+	switch {
+	case value != value:
+		value = float32(math.NaN())
+	case value == 0:
+		value = 0
+	}
+	return AppendKeyUint(key, orderedFloatBits(uint64(math.Float32bits(value)), 32), 4)
+}
+
+func ReadKeyFloat32(key []byte) (float32, []byte, error) {
+	u, rest, err := ReadKeyUint(key, 4)
+	if err != nil {
+		return 0, nil, err
+	}
+	return math.Float32frombits(uint32(floatBits(u, 32))), rest, nil
+}
+
+// AppendKeyBytes appends an escaped byte string: 0x00 bytes are followed by 0xFF and
+// the string ends with 0x00 0x01.
+func AppendKeyBytes(key []byte, value []byte) []byte {
+	for _, b := range value {
+		key = append(key, b)
+		if b == keyEscape {
+			key = append(key, keyEscaped)
+		}
+	}
+	return append(key, keyEscape, keyTerminator)
+}
+
+func ReadKeyBytes(key []byte) ([]byte, []byte, error) {
+	value := []byte{}
+	for i := 0; i < len(key); i++ {
+		if key[i] != keyEscape {
+			value = append(value, key[i])
+			continue
+		}
+		if i+1 >= len(key) {
+			return nil, nil, ErrInvalidKey
+		}
+		switch key[i+1] {
+		case keyEscaped:
+			value = append(value, keyEscape)
+			i++
+		case keyTerminator:
+			return value, key[i+2:], nil
+		default:
+			return nil, nil, ErrInvalidKey
+		}
+	}
+	return nil, nil, ErrInvalidKey
+}
+
+// InvertKey flips every bit of a key part, reversing its order. Used for descending columns.
+func InvertKey(part []byte) {
+	for i := range part {
+		part[i] = ^part[i]
+	}
+}
+
+func signBit(size int) uint64 {
+	return 1 << (8*size - 1)
+}
+
+// orderedFloatBits makes the unsigned order of IEEE 754 bits match the float order:
+// negative values have all their bits flipped, positive ones only their sign bit.
+func orderedFloatBits(bits uint64, size int) uint64 {
+	sign := uint64(1) << (size - 1)
+	if bits&sign != 0 {
+		return ^bits & (sign<<1 - 1)
+	}
+	return bits | sign
+}
+
+func floatBits(ordered uint64, size int) uint64 {
+	sign := uint64(1) << (size - 1)
+	if ordered&sign != 0 {
+		return ordered &^ sign
+	}
+	return ^ordered & (sign<<1 - 1)
+}
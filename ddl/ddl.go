@@ -0,0 +1,179 @@
+package ddl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/tinydb/buffer"
+	"github.com/tinydb/catalog"
+	"github.com/tinydb/freespace"
+	"github.com/tinydb/heap"
+	"github.com/tinydb/storage"
+)
+
+var (
+	ErrTableAlreadyExists = errors.New("table already exists")
+	ErrTableNotFound      = errors.New("table not found")
+	ErrReservedTableName  = errors.New("table name is reserved for system or free space map relations")
+)
+
+// Executor runs data definition statements, keeping the catalog, the page directory,
+// relation and free space map files and the buffer pool consistent.
+// Statements are serialized. Callers must prevent data accesses to a table being dropped or truncated.
+type Executor struct {
+	catalog *catalog.Catalog
+	heap    *heap.Manager
+	buffers *buffer.Manager
+	mutex   *sync.Mutex
+}
+
+func NewExecutor(catalog *catalog.Catalog, heap *heap.Manager, buffers *buffer.Manager) *Executor {
+	return &Executor{
+		catalog: catalog,
+		heap:    heap,
+		buffers: buffers,
+		mutex:   &sync.Mutex{},
+	}
+}
+
+// OpenTables opens the files of every table of the catalog, to be called at startup.
+func (e *Executor) OpenTables(ctx context.Context) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	for _, table := range e.catalog.Relations() {
+		if err := e.heap.OpenRelation(ctx, table, table); err != nil {
+			return fmt.Errorf("failed to open table %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// CreateTable creates the table files, then records the table in the catalog.
+// Files left by a previous failed creation are emptied, and new files are removed if a later step fails.
+// System relation names and names ending like free space map relations are rejected since their relations would collide.
+func (e *Executor) CreateTable(ctx context.Context, table string, fields []catalog.Field) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if catalog.IsSystemRelation(table) || strings.HasSuffix(table, freespace.FsmRelationSuffix) {
+		return ErrReservedTableName
+	}
+	if _, err := e.catalog.GetLayout(table); err == nil {
+		return ErrTableAlreadyExists
+	}
+	layout, err := catalog.NewLayout(fields)
+	if err != nil {
+		return err
+	}
+
+	if err := e.heap.OpenRelation(ctx, table, table); err != nil {
+		err = fmt.Errorf("failed to create table files: %w", err)
+		if errors.Is(err, storage.ErrRelationAlreadyExists) {
+			// Opened by someone else, not ours to remove
+			return err
+		}
+		return e.rollbackCreate(ctx, table, err)
+	}
+	if err := e.heap.TruncateRelation(ctx, table); err != nil {
+		return e.rollbackCreate(ctx, table, fmt.Errorf("failed to empty leftover table files: %w", err))
+	}
+
+	if err := e.catalog.AddRelation(ctx, table, layout); err != nil {
+		if errors.Is(err, catalog.ErrRelationAlreadyExists) {
+			err = ErrTableAlreadyExists
+		}
+		return e.rollbackCreate(ctx, table, err)
+	}
+	return e.buffers.FlushRelation(table)
+}
+
+func (e *Executor) rollbackCreate(ctx context.Context, table string, err error) error {
+	if dropErr := e.heap.DropRelation(ctx, table); dropErr != nil {
+		return errors.Join(err, fmt.Errorf("failed to remove table files: %w", dropErr))
+	}
+	return err
+}
+
+// DropTable removes the table from the catalog, then deletes its files and buffered pages.
+// Buffered pages are discarded first so that a table still in use isn't dropped.
+func (e *Executor) DropTable(ctx context.Context, table string) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if _, err := e.catalog.GetLayout(table); err != nil {
+		return ErrTableNotFound
+	}
+
+	if err := e.buffers.InvalidateRelation(ctx, table); err != nil {
+		return err
+	}
+	if err := e.catalog.RemoveRelation(ctx, table); err != nil {
+		return err
+	}
+	if err := e.heap.DropRelation(ctx, table); err != nil {
+		return fmt.Errorf("table removed from catalog but its files weren't deleted: %w", err)
+	}
+	return nil
+}
+
+// TruncateTable removes all rows of the table, keeping its definition.
+func (e *Executor) TruncateTable(ctx context.Context, table string) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if _, err := e.catalog.GetLayout(table); err != nil {
+		return ErrTableNotFound
+	}
+	return e.heap.TruncateRelation(ctx, table)
+}
+
+// AddColumn adds a column to the table. Existing rows read the column default value.
+func (e *Executor) AddColumn(ctx context.Context, table string, field catalog.Field) error {
+	return e.alterTable(table, func() error {
+		return e.catalog.AddField(ctx, table, field)
+	})
+}
+
+// DropColumn removes a column from the table. Existing rows keep its value until they are rewritten.
+func (e *Executor) DropColumn(ctx context.Context, table string, column string) error {
+	return e.alterTable(table, func() error {
+		return e.catalog.DropField(ctx, table, column)
+	})
+}
+
+// RenameColumn changes the name of a column of the table.
+func (e *Executor) RenameColumn(ctx context.Context, table string, column string, newName string) error {
+	return e.alterTable(table, func() error {
+		return e.catalog.RenameField(ctx, table, column, newName)
+	})
+}
+
+// SetColumnNullable changes the nullability of a column of the table,
+// which fails if the column is made non nullable while rows hold null values for it.
+func (e *Executor) SetColumnNullable(ctx context.Context, table string, column string, nullable bool) error {
+	return e.alterTable(table, func() error {
+		return e.catalog.SetFieldNullable(ctx, table, column, nullable)
+	})
+}
+
+// SetColumnType changes the type of a column of the table, such as a second precision datetime
+// to a timestamp. Existing rows are converted when they are rewritten.
+func (e *Executor) SetColumnType(ctx context.Context, table string, column string, fieldType catalog.FieldType) error {
+	return e.alterTable(table, func() error {
+		return e.catalog.SetFieldType(ctx, table, column, fieldType)
+	})
+}
+
+func (e *Executor) alterTable(table string, alter func() error) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if _, err := e.catalog.GetLayout(table); err != nil {
+		return ErrTableNotFound
+	}
+	return alter()
+}
@@ -0,0 +1,141 @@
+package ddl
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tinydb/buffer"
+	"github.com/tinydb/catalog"
+	"github.com/tinydb/freespace"
+	"github.com/tinydb/heap"
+	"github.com/tinydb/storage"
+)
+
+// openTestExecutor opens the database stored in dir, as done at startup.
+func openTestExecutor(tb testing.TB, dir string) *Executor {
+	tb.Helper()
+
+	ctx := context.Background()
+	store := storage.NewStorageManager()
+	directory := storage.NewPageDirectory(dir)
+	buffers := buffer.NewBufferManager(store, directory)
+	heapManager := heap.NewHeapManager(store, directory, buffers, freespace.NewFreeSpaceManager(store, directory, buffers))
+	cat, err := catalog.OpenCatalog(ctx, heapManager, buffers)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	executor := NewExecutor(cat, heapManager, buffers)
+	if err := executor.OpenTables(ctx); err != nil {
+		tb.Fatal(err)
+	}
+	return executor
+}
+
+func TestCreateTableRejectsSystemRelationNames(t *testing.T) {
+	dir := t.TempDir()
+	executor := openTestExecutor(t, dir)
+	ctx := context.Background()
+	fields := []catalog.Field{{Name: "a", Type: catalog.Int32Type}}
+
+	for _, table := range []string{catalog.TablesRelation, catalog.ColumnsRelation, catalog.IndexesRelation, "orders" + freespace.FsmRelationSuffix} {
+		if err := executor.CreateTable(ctx, table, fields); !errors.Is(err, ErrReservedTableName) {
+			t.Fatalf("creating table %s: got error %v, want %v", table, err, ErrReservedTableName)
+		}
+	}
+
+	// System relations are still usable
+	if err := executor.CreateTable(ctx, "orders", fields); err != nil {
+		t.Fatal(err)
+	}
+	if err := executor.buffers.FlushAll(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := openTestExecutor(t, dir).catalog.GetLayout("orders"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateTableRetryAfterInvalidDefault(t *testing.T) {
+	dir := t.TempDir()
+	executor := openTestExecutor(t, dir)
+	ctx := context.Background()
+
+	err := executor.CreateTable(ctx, "x", []catalog.Field{
+		{Name: "a", Type: catalog.Int32Type},
+		{Name: "b", Type: catalog.Int32Type, Default: "oops"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "field b") {
+		t.Fatalf("got error %v, want an invalid default value of field b", err)
+	}
+	if err := executor.CreateTable(ctx, "x", []catalog.Field{{Name: "a", Type: catalog.Int32Type}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := executor.buffers.FlushAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	layout, err := openTestExecutor(t, dir).catalog.GetLayout("x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(layout.Fields) != 1 {
+		t.Fatalf("got %d fields, want 1", len(layout.Fields))
+	}
+}
+
+func TestAlterTableLayoutVersions(t *testing.T) {
+	dir := t.TempDir()
+	executor := openTestExecutor(t, dir)
+	ctx := context.Background()
+
+	err := executor.CreateTable(ctx, "x", []catalog.Field{
+		{Name: "a", Type: catalog.Int32Type},
+		{Name: "b", Type: catalog.TimestampType, Nullable: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Alterations changing nothing don't record a layout version
+	if err := executor.SetColumnNullable(ctx, "x", "b", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := executor.SetColumnType(ctx, "x", "b", catalog.TimestampType); err != nil {
+		t.Fatal(err)
+	}
+	checkLayoutVersion(t, executor, "x", 0)
+
+	// Column tuples stored before a failure aren't loaded along with the next layout version
+	tooLong := strings.Repeat("c", storage.MaxTupleSize)
+	if err := executor.AddColumn(ctx, "x", catalog.Field{Name: tooLong, Type: catalog.Int32Type, Nullable: true}); !errors.Is(err, storage.ErrTupleTooLarge) {
+		t.Fatalf("got error %v, want %v", err, storage.ErrTupleTooLarge)
+	}
+	checkLayoutVersion(t, executor, "x", 0)
+	if err := executor.AddColumn(ctx, "x", catalog.Field{Name: "c", Type: catalog.Int32Type, Nullable: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := executor.buffers.FlushAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	executor = openTestExecutor(t, dir)
+	layout := checkLayoutVersion(t, executor, "x", 1)
+	if len(layout.Fields) != 3 {
+		t.Fatalf("got %d fields, want 3", len(layout.Fields))
+	}
+}
+
+func checkLayoutVersion(tb testing.TB, executor *Executor, table string, version uint16) catalog.Layout {
+	tb.Helper()
+
+	layout, err := executor.catalog.GetLayout(table)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if layout.Version != version {
+		tb.Fatalf("got layout version %d, want %d", layout.Version, version)
+	}
+	return layout
+}
@@ -0,0 +1,154 @@
+package freespace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tinydb/storage"
+)
+
+const (
+	extensionPagesPerWaiter = 16  // Pages added to an extension for each goroutine waiting to extend
+	maxExtensionPages       = 512 // Upper bound of pages added by a single extension
+)
+
+var (
+	ErrSizeTooLarge = errors.New("requested size exceeds an empty page free space")
+)
+
+// GetPageForInsert returns a page of the relation having at least reqSize bytes of free space,
+// preferably at or after the near page id, see GetFreePageIdNear.
+// When the free space map has no fitting page, the relation is extended with new empty pages,
+// more of them when other goroutines are waiting to extend it too.
+// The free space is checked on the page itself, but it may be used by concurrent inserts before
+// the caller latches the page.
+func (f *FreeSpaceManager) GetPageForInsert(ctx context.Context, relation string, reqSize uint16, near uint32) (storage.PageId, error) {
+	if reqSize > storage.EmptyPageFreeSpace {
+		return storage.PageId{}, ErrSizeTooLarge
+	}
+
+	relFsm, err := f.getRelationFsm(relation)
+	if err != nil {
+		return storage.PageId{}, err
+	}
+
+	for {
+		pageId, err := f.GetFreePageIdNear(ctx, relation, reqSize, near)
+		if errors.Is(err, ErrNoSpace) {
+			return f.extendRelation(ctx, relFsm, relation, reqSize, near)
+		}
+		if err != nil {
+			return storage.PageId{}, err
+		}
+
+		fits, err := f.checkFreeSpace(ctx, relFsm, pageId, reqSize)
+		if err != nil {
+			return storage.PageId{}, err
+		}
+		if fits {
+			return pageId, nil
+		}
+	}
+}
+
+// checkFreeSpace reads the page free space and records it in the free space map if it doesn't fit
+// reqSize bytes along with the space reserved by the fill factor.
+func (f *FreeSpaceManager) checkFreeSpace(ctx context.Context, relFsm *relationFsm, pageId storage.PageId, reqSize uint16) (bool, error) {
+	guard, err := f.buffers.FetchRead(ctx, pageId)
+	if err != nil {
+		return false, fmt.Errorf("failed to get page: %w", err)
+	}
+	header, err := guard.Page().ReadPageHeader()
+	if err := guard.Release(); err != nil {
+		return false, err
+	}
+	if err != nil {
+		return false, err
+	}
+
+	relFsm.mutex.RLock()
+	required := relFsm.requiredSpace(reqSize)
+	relFsm.mutex.RUnlock()
+	if uint32(header.FreeSpace) >= required {
+		return true, nil
+	}
+	// Stale entry
+	return false, f.UpdateFreeSpace(ctx, pageId, header.FreeSpace)
+}
+
+// extendRelation appends empty pages to the relation and returns the first one.
+// Pages are added to the free space map one by one: when adding one fails, the next ones are still added,
+// so that they can be used by later inserts.
+func (f *FreeSpaceManager) extendRelation(ctx context.Context, relFsm *relationFsm, relation string, reqSize uint16, near uint32) (storage.PageId, error) {
+	relFsm.extendWaiters.Add(1)
+	relFsm.extendMutex.Lock()
+	defer relFsm.extendMutex.Unlock()
+	waiters := relFsm.extendWaiters.Add(-1)
+
+	// The relation may have been extended while waiting
+	for {
+		pageId, err := f.GetFreePageIdNear(ctx, relation, reqSize, near)
+		if errors.Is(err, ErrNoSpace) {
+			break
+		}
+		if err != nil {
+			return storage.PageId{}, err
+		}
+		fits, err := f.checkFreeSpace(ctx, relFsm, pageId, reqSize)
+		if err != nil {
+			return storage.PageId{}, err
+		}
+		if fits {
+			return pageId, nil
+		}
+	}
+
+	fpath, err := f.directory.GetFilePath(relation)
+	if err != nil {
+		return storage.PageId{}, err
+	}
+
+	count := min(1+uint32(waiters)*extensionPagesPerWaiter, maxExtensionPages)
+	offset, err := f.store.ExtendFile(fpath, count)
+	if err != nil {
+		return storage.PageId{}, fmt.Errorf("failed to extend relation file: %w", err)
+	}
+
+	firstId := offset / storage.PageSize
+	var errs []error
+	for i := range count {
+		pageId := storage.PageId{Id: firstId + i, Relation: relation}
+		if err := f.addPage(ctx, pageId, offset+i*storage.PageSize); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) != 0 {
+		return storage.PageId{}, errors.Join(errs...)
+	}
+	return storage.PageId{Id: firstId, Relation: relation}, nil
+}
+
+// addPage registers a new page of the relation file, writes its header and records it as empty.
+func (f *FreeSpaceManager) addPage(ctx context.Context, pageId storage.PageId, offset uint32) error {
+	if _, err := f.directory.RegisterPage(pageId, offset); err != nil {
+		return fmt.Errorf("failed to register page: %w", err)
+	}
+	if err := f.initPage(ctx, pageId); err != nil {
+		return err
+	}
+	return f.UpdateFreeSpace(ctx, pageId, storage.EmptyPageFreeSpace)
+}
+
+// initPage writes the header of a new empty page.
+func (f *FreeSpaceManager) initPage(ctx context.Context, pageId storage.PageId) error {
+	guard, err := f.buffers.FetchWrite(ctx, pageId)
+	if err != nil {
+		return fmt.Errorf("failed to get new page: %w", err)
+	}
+	if err := guard.Page().Init(storage.PageTypeValues); err != nil {
+		guard.Release()
+		return err
+	}
+	return guard.Release()
+}
@@ -1,142 +1,100 @@
 package freespace
 
-type fsmNode struct {
-	id            *uint32 // Nil for non-leaf nodes
-	maxSpace      uint16
-	childrenCount uint32
-	parent        *fsmNode
-	left          *fsmNode
-	right         *fsmNode
+// categoryTree is a complete binary tree of free space categories stored in an array:
+// node i has children 2i+1 and 2i+2 and leaves are stored after all inner nodes.
+// Each inner node holds the max category of its children, so the root tells whether any leaf fits.
+// Leaves count must be a power of 2.
+type categoryTree struct {
+	nodes  []byte
+	leaves int
 }
 
-type freeSpaceMap struct {
-	root      *fsmNode
-	leafNodes map[uint32]*fsmNode
+func newCategoryTree(leaves int) categoryTree {
+	return categoryTree{
+		nodes:  make([]byte, 2*leaves-1),
+		leaves: leaves,
+	}
 }
 
-func newFreeSpaceMap() *freeSpaceMap {
-	return &freeSpaceMap{
-		leafNodes: map[uint32]*fsmNode{},
-	}
+func (t categoryTree) root() byte {
+	return t.nodes[0]
 }
 
-func (f *freeSpaceMap) getMatch(size uint16) (uint32, bool) {
-	node := f.root
-	if node == nil || node.maxSpace < size {
-		return 0, false
-	}
+func (t categoryTree) get(leaf int) byte {
+	return t.nodes[t.leaves-1+leaf]
+}
 
-	for node.id == nil {
-		if node.left != nil && node.left.maxSpace > size {
-			node = node.left
-		} else if node.right != nil {
-			node = node.right
+// set updates a leaf category and its ancestors, it returns whether the root category changed.
+func (t categoryTree) set(leaf int, category byte) bool {
+	prevRoot := t.nodes[0]
+	node := t.leaves - 1 + leaf
+	t.nodes[node] = category
+	for node > 0 {
+		node = (node - 1) / 2
+		maxCategory := max(t.nodes[2*node+1], t.nodes[2*node+2])
+		if t.nodes[node] == maxCategory {
+			// Ancestors are already up to date
+			break
 		}
+		t.nodes[node] = maxCategory
 	}
-
-	return *node.id, true
+	return t.nodes[0] != prevRoot
 }
 
-func (f *freeSpaceMap) setFreeSpace(id uint32, free uint16) {
-	if f.root == nil {
-		f.root = &fsmNode{
-			id:       &id,
-			maxSpace: free,
-		}
-		f.leafNodes[id] = f.root
-		return
+// searchFrom returns the leftmost leaf at or after start having at least the given category.
+func (t categoryTree) searchFrom(start int, category byte) (int, bool) {
+	if start >= t.leaves {
+		return 0, false
 	}
 
-	node, found := f.leafNodes[id]
-	if !found {
-		node = f.createNode(id, free)
-		f.leafNodes[id] = node
+	node := t.leaves - 1 + start
+	if t.nodes[node] >= category {
+		return start, true
 	}
 
-	// Update parent chain
-	for node.parent != nil {
-		if node.parent.left != nil && (node.parent.right == nil || node.parent.left.maxSpace > node.parent.right.maxSpace) {
-			node.maxSpace = node.parent.left.maxSpace
-		} else if node.parent.right != nil && (node.parent.left == nil || node.parent.right.maxSpace > node.parent.left.maxSpace) {
-			node.maxSpace = node.parent.right.maxSpace
-		} else {
-			// No need to up date higher
-			break
+	// Right siblings of the start leaf ancestors cover all leaves after it, closest first
+	for node > 0 {
+		if node%2 == 1 && t.nodes[node+1] >= category {
+			return t.descend(node+1, category), true
 		}
-
-		node = node.parent
+		node = (node - 1) / 2
 	}
+	return 0, false
 }
 
-func (f *freeSpaceMap) createNode(id uint32, free uint16) *fsmNode {
-	node := f.root
-	for {
-		if node.left == nil && node.right == nil {
-			// Root without children
-			if node.parent == nil {
-				// Create a new root and put existing node as well as new node under it
-				f.root = &fsmNode{
-					childrenCount: 2,
-					left:          node,
-				}
-				node.parent = f.root
-				f.root.right = &fsmNode{
-					id:       &id,
-					maxSpace: free,
-					parent:   f.root,
-				}
-				return f.root.right
-			}
-
-			// Node without children
-			// Create new parent node to store both children
-			newParent := &fsmNode{
-				childrenCount: 2,
-				parent:        node.parent,
-				left:          node,
-			}
-			if node.parent.left == node {
-				node.parent.left = newParent
-			} else {
-				node.parent.right = newParent
-			}
-			node.parent = newParent
-			newParent.right = &fsmNode{
-				id:       &id,
-				maxSpace: free,
-				parent:   newParent,
-			}
-			return newParent.right
+// descend returns the leftmost leaf under node having at least the given category, node must hold it.
+func (t categoryTree) descend(node int, category byte) int {
+	for node < t.leaves-1 {
+		left := 2*node + 1
+		if t.nodes[left] >= category {
+			node = left
+		} else {
+			node = left + 1
 		}
+	}
+	return node - (t.leaves - 1)
+}
 
-		if node.left == nil {
-			// Create new child on empty left side
-			node.childrenCount++
-			node.left = &fsmNode{
-				id:       &id,
-				maxSpace: free,
-				parent:   node,
-			}
-			return node.left
-		}
+// grow returns a tree with at least the given leaves count, holding the same leaves.
+func (t categoryTree) grow(minLeaves int) categoryTree {
+	if minLeaves <= t.leaves {
+		return t
+	}
 
-		if node.right == nil {
-			// Create new child on empty right side
-			node.childrenCount++
-			node.right = &fsmNode{
-				id:       &id,
-				maxSpace: free,
-				parent:   node,
-			}
-			return node.right
-		}
+	leaves := t.leaves
+	for leaves < minLeaves {
+		leaves *= 2
+	}
 
-		if node.left.childrenCount < node.right.childrenCount {
-			node.childrenCount++
-			node = node.left
-		} else {
-			node.childrenCount++
-			node = node.right
-		}
+	grown := newCategoryTree(leaves)
+	copy(grown.nodes[leaves-1:], t.nodes[t.leaves-1:])
+	grown.rebuildInnerNodes()
+	return grown
+}
+
+// rebuildInnerNodes recomputes all inner nodes from the leaves.
+func (t categoryTree) rebuildInnerNodes() {
+	for node := t.leaves - 2; node >= 0; node-- {
+		t.nodes[node] = max(t.nodes[2*node+1], t.nodes[2*node+2])
 	}
 }
@@ -1,37 +1,342 @@
 package freespace
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
 
+	"github.com/tinydb/buffer"
 	"github.com/tinydb/storage"
 )
 
+const (
+	FsmRelationSuffix = "_fsm"
+	categorySize      = 32                                        // Free space bytes per category unit
+	leavesPerPage     = 2048                                      // Relation pages tracked by a _fsm page, its tree uses 2*leavesPerPage-1 bytes
+	emptyPageCategory = storage.EmptyPageFreeSpace / categorySize // Highest category of a page
+	minFillFactor     = 10
+	maxFillFactor     = 100
+)
+
 var (
 	ErrRelationNotExists = errors.New("relation doesn't exist")
 	ErrNoSpace           = errors.New("no space available for requested size")
+	ErrInvalidFillFactor = fmt.Errorf("fill factor must be between %d and %d", minFillFactor, maxFillFactor)
 )
 
+// relationFsm is the free space info of a relation.
+type relationFsm struct {
+	fsmRelation string
+	fsmPages    uint32       // Pages count of the _fsm relation
+	upper       categoryTree // Root category of each _fsm page
+	fillFactor  uint8        // Percentage of a page that inserts may fill
+	mutex       *sync.RWMutex
+
+	extendMutex   *sync.Mutex   // Serializes the relation extensions
+	extendWaiters *atomic.Int32 // Goroutines waiting to extend the relation
+}
+
+// FreeSpaceManager keeps track of the free space of relations pages.
+// Free space is stored as 1 byte categories (free bytes / categorySize) in the relation's _fsm relation,
+// accessed through the buffer manager. Each _fsm page holds a category tree whose leaf N is
+// the category of page P*leavesPerPage+N, P being the _fsm page id.
+// Only the root category of each _fsm page is kept in memory, in an upper tree built at Init.
+// Dirty _fsm pages are written lazily by the buffer manager.
+// Since the free space map only gives hints, it can be rebuilt from the relation pages.
+// It is safe for concurrent use; each relation is locked independently.
 type FreeSpaceManager struct {
-	relationsMap map[string]freeSpaceMap
+	store        *storage.Manager
+	directory    *storage.PageDirectory
+	buffers      *buffer.Manager
+	relationsMap map[string]*relationFsm
+	mutex        *sync.RWMutex
+}
+
+func NewFreeSpaceManager(store *storage.Manager, directory *storage.PageDirectory, buffers *buffer.Manager) *FreeSpaceManager {
+	return &FreeSpaceManager{
+		store:        store,
+		directory:    directory,
+		buffers:      buffers,
+		relationsMap: map[string]*relationFsm{},
+		mutex:        &sync.RWMutex{},
+	}
+}
+
+// Init loads the free space map of a relation, creating its _fsm relation file if it doesn't exist.
+func (f *FreeSpaceManager) Init(ctx context.Context, mainRel string, relation string) error {
+	fsmRelation := relation + FsmRelationSuffix
+	fsmPages, err := storage.OpenRelationFile(f.store, f.directory, mainRel, fsmRelation)
+	if err != nil {
+		return fmt.Errorf("failed to open free space map relation: %w", err)
+	}
+
+	relFsm := &relationFsm{
+		fsmRelation: fsmRelation,
+		fsmPages:    fsmPages,
+		upper:       newCategoryTree(1).grow(int(fsmPages)),
+		fillFactor:  maxFillFactor,
+		mutex:       &sync.RWMutex{},
+
+		extendMutex:   &sync.Mutex{},
+		extendWaiters: &atomic.Int32{},
+	}
+	for fsmPageId := range fsmPages {
+		guard, err := f.buffers.FetchRead(ctx, storage.PageId{Id: fsmPageId, Relation: fsmRelation})
+		if err != nil {
+			return f.closeFsmRelation(ctx, fsmRelation, fmt.Errorf("failed to get free space map page: %w", err))
+		}
+		relFsm.upper.set(int(fsmPageId), pageTree(guard.Page()).root())
+		if err := guard.Release(); err != nil {
+			return f.closeFsmRelation(ctx, fsmRelation, err)
+		}
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.relationsMap[relation] = relFsm
+	return nil
+}
+
+// closeFsmRelation unregisters a _fsm relation that failed to load, discarding its buffered pages.
+func (f *FreeSpaceManager) closeFsmRelation(ctx context.Context, fsmRelation string, err error) error {
+	if invalidateErr := f.buffers.InvalidateRelation(ctx, fsmRelation); invalidateErr != nil {
+		return errors.Join(err, invalidateErr)
+	}
+	f.directory.UnregisterFile(fsmRelation)
+	return err
+}
+
+// GetFreePageId returns a page of the relation with at least reqSize bytes of free space,
+// on top of the space reserved by the relation fill factor. A request exceeding the fill factor allowance
+// is given an empty page, as are requests beyond the highest category of non empty pages.
+func (f *FreeSpaceManager) GetFreePageId(ctx context.Context, relation string, reqSize uint16) (storage.PageId, error) {
+	return f.GetFreePageIdNear(ctx, relation, reqSize, 0)
+}
+
+// GetFreePageIdNear is like GetFreePageId but returns the first fitting page at or after the near page id,
+// wrapping around to the start of the relation, so that related tuples are stored close to each other.
+func (f *FreeSpaceManager) GetFreePageIdNear(ctx context.Context, relation string, reqSize uint16, near uint32) (storage.PageId, error) {
+	relFsm, err := f.getRelationFsm(relation)
+	if err != nil {
+		return storage.PageId{}, err
+	}
+
+	if reqSize > storage.EmptyPageFreeSpace {
+		return storage.PageId{}, ErrNoSpace
+	}
+
+	relFsm.mutex.RLock()
+	defer relFsm.mutex.RUnlock()
+
+	// Removed pages are recorded with no free space, never match them
+	category := byte(min(max(requestCategory(relFsm.requiredSpace(reqSize)), 1), emptyPageCategory))
+
+	fsmPageId, leafStart := int(near/leavesPerPage), int(near%leavesPerPage)
+	wrapped := near == 0
+	for {
+		foundPageId, found := relFsm.upper.searchFrom(fsmPageId, category)
+		if !found {
+			if wrapped {
+				return storage.PageId{}, ErrNoSpace
+			}
+			fsmPageId, leafStart, wrapped = 0, 0, true
+			continue
+		}
+		if foundPageId != fsmPageId {
+			leafStart = 0
+		}
+
+		guard, err := f.buffers.FetchRead(ctx, storage.PageId{Id: uint32(foundPageId), Relation: relFsm.fsmRelation})
+		if err != nil {
+			return storage.PageId{}, fmt.Errorf("failed to get free space map page: %w", err)
+		}
+		leaf, found := pageTree(guard.Page()).searchFrom(leafStart, category)
+		if err := guard.Release(); err != nil {
+			return storage.PageId{}, err
+		}
+		if found {
+			return storage.PageId{
+				Id:       uint32(foundPageId)*leavesPerPage + uint32(leaf),
+				Relation: relation,
+			}, nil
+		}
+
+		// Fitting pages of this _fsm page are all before the hint
+		fsmPageId, leafStart = foundPageId+1, 0
+	}
 }
 
-func (f *FreeSpaceManager) Init(relation string) {
-	f.relationsMap[relation] = freeSpaceMap{}
+// SetFillFactor sets the percentage of a page that inserts may fill,
+// the remaining space being kept for in-place updates of the page tuples.
+func (f *FreeSpaceManager) SetFillFactor(relation string, fillFactor uint8) error {
+	if fillFactor < minFillFactor || fillFactor > maxFillFactor {
+		return ErrInvalidFillFactor
+	}
+
+	relFsm, err := f.getRelationFsm(relation)
+	if err != nil {
+		return err
+	}
+
+	relFsm.mutex.Lock()
+	defer relFsm.mutex.Unlock()
+	relFsm.fillFactor = fillFactor
+	return nil
 }
 
-func (f *FreeSpaceManager) GetFreePageId(relation string, reqSize uint16) (storage.PageId, error) {
-	fsm, found := f.relationsMap[relation]
+// UpdateFreeSpace records the new free space of a page.
+func (f *FreeSpaceManager) UpdateFreeSpace(ctx context.Context, pageId storage.PageId, free uint16) error {
+	relFsm, err := f.getRelationFsm(pageId.Relation)
+	if err != nil {
+		return err
+	}
+
+	relFsm.mutex.Lock()
+	defer relFsm.mutex.Unlock()
+	return f.setFreeSpace(ctx, relFsm, pageId.Id, free)
+}
+
+// RemovePage stops tracking a page which doesn't belong to the relation anymore.
+func (f *FreeSpaceManager) RemovePage(ctx context.Context, pageId storage.PageId) error {
+	return f.UpdateFreeSpace(ctx, pageId, 0)
+}
+
+// DropRelation forgets the free space map of a relation and deletes its _fsm relation.
+func (f *FreeSpaceManager) DropRelation(ctx context.Context, relation string) error {
+	f.mutex.Lock()
+	relFsm, found := f.relationsMap[relation]
 	if !found {
-		return storage.PageId{}, ErrRelationNotExists
+		f.mutex.Unlock()
+		return ErrRelationNotExists
+	}
+	delete(f.relationsMap, relation)
+	f.mutex.Unlock()
+
+	// Wait for operations in progress on the relation
+	relFsm.mutex.Lock()
+	defer relFsm.mutex.Unlock()
+	if err := f.buffers.DropRelation(ctx, relFsm.fsmRelation); err != nil {
+		f.mutex.Lock()
+		f.relationsMap[relation] = relFsm
+		f.mutex.Unlock()
+		return fmt.Errorf("failed to drop free space map relation: %w", err)
+	}
+	return nil
+}
+
+// TruncateRelation empties the free space map of a relation, along with its _fsm relation.
+func (f *FreeSpaceManager) TruncateRelation(ctx context.Context, relation string) error {
+	relFsm, err := f.getRelationFsm(relation)
+	if err != nil {
+		return err
+	}
+
+	relFsm.extendMutex.Lock()
+	defer relFsm.extendMutex.Unlock()
+	relFsm.mutex.Lock()
+	defer relFsm.mutex.Unlock()
+
+	if err := f.buffers.TruncateRelation(ctx, relFsm.fsmRelation); err != nil {
+		return fmt.Errorf("failed to truncate free space map relation: %w", err)
 	}
+	relFsm.fsmPages = 0
+	relFsm.upper = newCategoryTree(1)
+	return nil
+}
+
+func (f *FreeSpaceManager) getRelationFsm(relation string) (*relationFsm, error) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
 
-	id, found := fsm.getMatch(reqSize)
+	relFsm, found := f.relationsMap[relation]
 	if !found {
-		return storage.PageId{}, ErrNoSpace
+		return nil, ErrRelationNotExists
+	}
+	return relFsm, nil
+}
+
+// setFreeSpace records the free space of a page, in memory and in the _fsm relation.
+// The relation free space map must be locked by the caller.
+func (f *FreeSpaceManager) setFreeSpace(ctx context.Context, relFsm *relationFsm, id uint32, free uint16) error {
+	fsmPageId := storage.PageId{
+		Id:       id / leavesPerPage,
+		Relation: relFsm.fsmRelation,
+	}
+	if fsmPageId.Id >= relFsm.fsmPages {
+		if err := f.extendFsmRelation(relFsm, fsmPageId.Id+1); err != nil {
+			return err
+		}
+	}
+
+	guard, err := f.buffers.FetchWrite(ctx, fsmPageId)
+	if err != nil {
+		return fmt.Errorf("failed to get free space map page: %w", err)
 	}
+	tree := pageTree(guard.Page())
+	rootChanged := tree.set(int(id%leavesPerPage), freeSpaceCategory(free))
+	root := tree.root()
+	if err := guard.Release(); err != nil {
+		return err
+	}
+
+	if rootChanged {
+		relFsm.upper.set(int(fsmPageId.Id), root)
+	}
+	return nil
+}
+
+// extendFsmRelation grows a _fsm relation to the given pages count, with zeroed pages.
+func (f *FreeSpaceManager) extendFsmRelation(relFsm *relationFsm, pageCount uint32) error {
+	fpath, err := f.directory.GetFilePath(relFsm.fsmRelation)
+	if err != nil {
+		return err
+	}
+
+	offset, err := f.store.ExtendFile(fpath, pageCount-relFsm.fsmPages)
+	if err != nil {
+		return fmt.Errorf("failed to extend free space map file: %w", err)
+	}
+
+	relFsm.upper = relFsm.upper.grow(int(pageCount))
+	for id := relFsm.fsmPages; id < pageCount; id++ {
+		pageOffset := offset + (id-relFsm.fsmPages)*storage.PageSize
+		_, err := f.directory.RegisterPage(storage.PageId{Id: id, Relation: relFsm.fsmRelation}, pageOffset)
+		if err != nil {
+			return fmt.Errorf("failed to register free space map page: %w", err)
+		}
+	}
+	relFsm.fsmPages = pageCount
+	return nil
+}
+
+// requiredSpace returns the free space a page needs for inserting reqSize bytes, including the space
+// reserved by the fill factor, up to the free space of an empty page.
+// The relation free space map must be locked by the caller.
+func (r *relationFsm) requiredSpace(reqSize uint16) uint32 {
+	reserved := uint32(storage.PageSize) * uint32(maxFillFactor-r.fillFactor) / maxFillFactor
+	return min(uint32(reqSize)+reserved, storage.EmptyPageFreeSpace)
+}
+
+// pageTree returns the category tree stored in a _fsm page, backed by the page data.
+func pageTree(page *storage.Page) categoryTree {
+	return categoryTree{
+		nodes:  page.Data[:2*leavesPerPage-1],
+		leaves: leavesPerPage,
+	}
+}
+
+// freeSpaceCategory rounds free space down, a page is guaranteed to have at least its category space.
+// The empty page category is kept for empty pages, so that it fits any request it is given.
+func freeSpaceCategory(free uint16) byte {
+	if free >= storage.EmptyPageFreeSpace {
+		return emptyPageCategory
+	}
+	return byte(min(free/categorySize, emptyPageCategory-1))
+}
 
-	return storage.PageId{
-		Id:       id,
-		Relation: relation,
-	}, nil
+// requestCategory rounds requested space up.
+func requestCategory(reqSize uint32) uint32 {
+	return (reqSize + categorySize - 1) / categorySize
 }
@@ -0,0 +1,162 @@
+package freespace
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tinydb/storage"
+)
+
+// Discrepancy is a page whose free space map entry doesn't match the free space of its header.
+type Discrepancy struct {
+	PageId       storage.PageId
+	MapFreeSpace uint16 // Free space lower bound given by the map category
+	FreeSpace    uint16 // Free space of the page header, 0 for entries past the relation end
+}
+
+type Report struct {
+	Relation      string
+	PagesScanned  uint32
+	Discrepancies []Discrepancy
+}
+
+// Verify scans every page of the relation and reports the free space map entries not matching page headers.
+// The map isn't locked during the scan, so pages updated concurrently may be reported.
+func (f *FreeSpaceManager) Verify(ctx context.Context, relation string) (Report, error) {
+	relFsm, err := f.getRelationFsm(relation)
+	if err != nil {
+		return Report{}, err
+	}
+
+	categories, freeSpaces, err := f.scanRelation(ctx, relation)
+	if err != nil {
+		return Report{}, err
+	}
+
+	relFsm.mutex.RLock()
+	defer relFsm.mutex.RUnlock()
+	return f.compareFsmPages(ctx, relFsm, relation, categories, freeSpaces, false)
+}
+
+// Rebuild scans every page of the relation and rewrites its free space map from the page headers.
+// The returned report lists the entries that were wrong.
+// The map is locked during the scan, along with relation extensions: free space updates made
+// concurrently wait for the rebuild, instead of being overwritten by the scanned free space.
+func (f *FreeSpaceManager) Rebuild(ctx context.Context, relation string) (Report, error) {
+	relFsm, err := f.getRelationFsm(relation)
+	if err != nil {
+		return Report{}, err
+	}
+
+	// Same order as extendRelation
+	relFsm.extendMutex.Lock()
+	defer relFsm.extendMutex.Unlock()
+	relFsm.mutex.Lock()
+	defer relFsm.mutex.Unlock()
+
+	categories, freeSpaces, err := f.scanRelation(ctx, relation)
+	if err != nil {
+		return Report{}, err
+	}
+
+	fsmPages := (uint32(len(categories)) + leavesPerPage - 1) / leavesPerPage
+	if fsmPages > relFsm.fsmPages {
+		if err := f.extendFsmRelation(relFsm, fsmPages); err != nil {
+			return Report{}, err
+		}
+	}
+	return f.compareFsmPages(ctx, relFsm, relation, categories, freeSpaces, true)
+}
+
+// scanRelation reads the free space of every page header of the relation.
+func (f *FreeSpaceManager) scanRelation(ctx context.Context, relation string) ([]byte, []uint16, error) {
+	fpath, err := f.directory.GetFilePath(relation)
+	if err != nil {
+		return nil, nil, err
+	}
+	pageCount, err := f.store.PageCount(fpath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read relation file size: %w", err)
+	}
+
+	categories := make([]byte, pageCount)
+	freeSpaces := make([]uint16, pageCount)
+	for id := range pageCount {
+		guard, err := f.buffers.FetchRead(ctx, storage.PageId{Id: id, Relation: relation})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get page: %w", err)
+		}
+		header, err := guard.Page().ReadPageHeader()
+		if err := guard.Release(); err != nil {
+			return nil, nil, err
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		categories[id] = freeSpaceCategory(header.FreeSpace)
+		freeSpaces[id] = header.FreeSpace
+	}
+	return categories, freeSpaces, nil
+}
+
+// compareFsmPages compares every _fsm page entry to the scanned categories, overwriting them if fix is set.
+// Entries past the scanned pages are expected to be empty.
+// The relation free space map must be locked by the caller, exclusively if fix is set.
+func (f *FreeSpaceManager) compareFsmPages(ctx context.Context, relFsm *relationFsm, relation string, categories []byte, freeSpaces []uint16, fix bool) (Report, error) {
+	report := Report{
+		Relation:     relation,
+		PagesScanned: uint32(len(categories)),
+	}
+
+	for fsmPageId := range relFsm.fsmPages {
+		pageId := storage.PageId{Id: fsmPageId, Relation: relFsm.fsmRelation}
+		var tree categoryTree
+		var release func() error
+		if fix {
+			guard, err := f.buffers.FetchWrite(ctx, pageId)
+			if err != nil {
+				return Report{}, fmt.Errorf("failed to get free space map page: %w", err)
+			}
+			tree, release = pageTree(guard.Page()), guard.Release
+		} else {
+			guard, err := f.buffers.FetchRead(ctx, pageId)
+			if err != nil {
+				return Report{}, fmt.Errorf("failed to get free space map page: %w", err)
+			}
+			tree, release = pageTree(guard.Page()), guard.Release
+		}
+
+		for leaf := range leavesPerPage {
+			id := fsmPageId*leavesPerPage + uint32(leaf)
+			var category byte
+			var freeSpace uint16
+			if id < uint32(len(categories)) {
+				category, freeSpace = categories[id], freeSpaces[id]
+			}
+
+			mapCategory := tree.get(leaf)
+			if mapCategory == category {
+				continue
+			}
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{
+				PageId:       storage.PageId{Id: id, Relation: relation},
+				MapFreeSpace: uint16(mapCategory) * categorySize,
+				FreeSpace:    freeSpace,
+			})
+			if fix {
+				tree.nodes[tree.leaves-1+leaf] = category
+			}
+		}
+
+		if fix {
+			// Inner nodes may be wrong even if leaves aren't
+			tree.rebuildInnerNodes()
+			relFsm.upper.set(int(fsmPageId), tree.root())
+		}
+		if err := release(); err != nil {
+			return Report{}, err
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,232 @@
+package heap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tinydb/buffer"
+	"github.com/tinydb/freespace"
+	"github.com/tinydb/storage"
+)
+
+// RecordId locates a tuple in a relation.
+type RecordId struct {
+	PageId storage.PageId
+	Slot   uint16
+}
+
+func (r RecordId) String() string {
+	return fmt.Sprintf("%s:%d", r.PageId, r.Slot)
+}
+
+// Manager stores tuples in unordered relation pages, using the free space map to find room for inserts.
+type Manager struct {
+	store     *storage.Manager
+	directory *storage.PageDirectory
+	buffers   *buffer.Manager
+	freeSpace *freespace.FreeSpaceManager
+}
+
+func NewHeapManager(store *storage.Manager, directory *storage.PageDirectory, buffers *buffer.Manager, freeSpace *freespace.FreeSpaceManager) *Manager {
+	return &Manager{
+		store:     store,
+		directory: directory,
+		buffers:   buffers,
+		freeSpace: freeSpace,
+	}
+}
+
+// OpenRelation registers a relation and its free space map, creating their files if they don't exist.
+// The relation is unregistered if its free space map cannot be opened.
+func (m *Manager) OpenRelation(ctx context.Context, mainRel string, relation string) error {
+	if _, err := storage.OpenRelationFile(m.store, m.directory, mainRel, relation); err != nil {
+		return fmt.Errorf("failed to open relation: %w", err)
+	}
+	if err := m.freeSpace.Init(ctx, mainRel, relation); err != nil {
+		err = fmt.Errorf("failed to open relation free space map: %w", err)
+		if invalidateErr := m.buffers.InvalidateRelation(ctx, relation); invalidateErr != nil {
+			return errors.Join(err, invalidateErr)
+		}
+		m.directory.UnregisterFile(relation)
+		return err
+	}
+	return nil
+}
+
+// TruncateRelation removes all pages of a relation and empties its free space map.
+func (m *Manager) TruncateRelation(ctx context.Context, relation string) error {
+	if err := m.buffers.TruncateRelation(ctx, relation); err != nil {
+		return fmt.Errorf("failed to truncate relation: %w", err)
+	}
+	return m.freeSpace.TruncateRelation(ctx, relation)
+}
+
+// DropRelation deletes a relation and its free space map, along with their buffered pages.
+// Parts of a relation that aren't open, such as after a failed OpenRelation, are skipped.
+func (m *Manager) DropRelation(ctx context.Context, relation string) error {
+	if err := m.freeSpace.DropRelation(ctx, relation); err != nil && !errors.Is(err, freespace.ErrRelationNotExists) {
+		return err
+	}
+	if err := m.buffers.DropRelation(ctx, relation); err != nil && !errors.Is(err, storage.ErrRelationNotExists) {
+		return fmt.Errorf("failed to drop relation: %w", err)
+	}
+	return nil
+}
+
+func (m *Manager) Insert(ctx context.Context, relation string, tuple []byte) (RecordId, error) {
+	return m.InsertNear(ctx, relation, tuple, 0)
+}
+
+// InsertNear is like Insert but stores the tuple in the first fitting page at or after the near page id,
+// so that tuples inserted together or moved by a rewrite stay close to each other.
+func (m *Manager) InsertNear(ctx context.Context, relation string, tuple []byte, near uint32) (RecordId, error) {
+	if len(tuple) > storage.MaxTupleSize {
+		return RecordId{}, storage.ErrTupleTooLarge
+	}
+
+	reqSize := storage.TupleSpace(uint16(len(tuple)))
+	for {
+		pageId, err := m.freeSpace.GetPageForInsert(ctx, relation, reqSize, near)
+		if err != nil {
+			return RecordId{}, fmt.Errorf("failed to find page for insert: %w", err)
+		}
+
+		guard, err := m.buffers.FetchWrite(ctx, pageId)
+		if err != nil {
+			return RecordId{}, fmt.Errorf("failed to get page: %w", err)
+		}
+		page := guard.Page()
+		slot, insertErr := page.InsertTuple(tuple)
+		free := page.Header.FreeSpace
+		if err := guard.Release(); err != nil {
+			return RecordId{}, err
+		}
+		if insertErr != nil && !errors.Is(insertErr, storage.ErrPageFull) {
+			return RecordId{}, insertErr
+		}
+
+		// On a full page, the space was taken by a concurrent insert: record it and look again
+		if err := m.freeSpace.UpdateFreeSpace(ctx, pageId, free); err != nil {
+			return RecordId{}, err
+		}
+		if insertErr == nil {
+			return RecordId{PageId: pageId, Slot: slot}, nil
+		}
+	}
+}
+
+func (m *Manager) Read(ctx context.Context, id RecordId) ([]byte, error) {
+	guard, err := m.buffers.FetchRead(ctx, id.PageId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get page: %w", err)
+	}
+	defer guard.Release()
+	return guard.Page().ReadTuple(id.Slot)
+}
+
+// Update overwrites a tuple in place, the new tuple must have the same size. See Rewrite for other sizes.
+func (m *Manager) Update(ctx context.Context, id RecordId, tuple []byte) error {
+	guard, err := m.buffers.FetchWrite(ctx, id.PageId)
+	if err != nil {
+		return fmt.Errorf("failed to get page: %w", err)
+	}
+	defer guard.Release()
+	return guard.Page().UpdateTuple(id.Slot, tuple)
+}
+
+// Rewrite stores a new version of a tuple, which may have another size, and returns its record id.
+// The tuple is replaced in its page when it fits there, keeping its record id, otherwise it is
+// inserted in another page, as near as possible, before being deleted from its original one.
+func (m *Manager) Rewrite(ctx context.Context, id RecordId, tuple []byte) (RecordId, error) {
+	if len(tuple) > storage.MaxTupleSize {
+		return RecordId{}, storage.ErrTupleTooLarge
+	}
+
+	guard, err := m.buffers.FetchWrite(ctx, id.PageId)
+	if err != nil {
+		return RecordId{}, fmt.Errorf("failed to get page: %w", err)
+	}
+	page := guard.Page()
+	replaceErr := page.ReplaceTuple(id.Slot, tuple)
+	free := page.Header.FreeSpace
+	if err := guard.Release(); err != nil {
+		return RecordId{}, err
+	}
+	if replaceErr == nil {
+		return id, m.freeSpace.UpdateFreeSpace(ctx, id.PageId, free)
+	}
+	if !errors.Is(replaceErr, storage.ErrPageFull) {
+		return RecordId{}, replaceErr
+	}
+
+	// Moved: a crash in between leaves both versions rather than none
+	newId, err := m.InsertNear(ctx, id.PageId.Relation, tuple, id.PageId.Id)
+	if err != nil {
+		return RecordId{}, err
+	}
+	if err := m.Delete(ctx, id); err != nil {
+		return RecordId{}, fmt.Errorf("tuple moved to %s but its previous version wasn't deleted: %w", newId, err)
+	}
+	return newId, nil
+}
+
+func (m *Manager) Delete(ctx context.Context, id RecordId) error {
+	guard, err := m.buffers.FetchWrite(ctx, id.PageId)
+	if err != nil {
+		return fmt.Errorf("failed to get page: %w", err)
+	}
+	defer guard.Release()
+	return guard.Page().DeleteTuple(id.Slot)
+}
+
+// Scan calls fn with every tuple of the relation, page by page. A page is read latched while fn runs on its tuples.
+func (m *Manager) Scan(ctx context.Context, relation string, fn func(id RecordId, tuple []byte) error) error {
+	fpath, err := m.directory.GetFilePath(relation)
+	if err != nil {
+		return err
+	}
+	pageCount, err := m.store.PageCount(fpath)
+	if err != nil {
+		return fmt.Errorf("failed to read relation file size: %w", err)
+	}
+
+	for id := range pageCount {
+		pageId := storage.PageId{Id: id, Relation: relation}
+		guard, err := m.buffers.FetchRead(ctx, pageId)
+		if err != nil {
+			return fmt.Errorf("failed to get page: %w", err)
+		}
+		err = scanPage(guard.Page(), func(slot uint16, tuple []byte) error {
+			return fn(RecordId{PageId: pageId, Slot: slot}, tuple)
+		})
+		if releaseErr := guard.Release(); releaseErr != nil && err == nil {
+			err = releaseErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func scanPage(page *storage.Page, fn func(slot uint16, tuple []byte) error) error {
+	header, err := page.ReadPageHeader()
+	if err != nil {
+		return err
+	}
+
+	for slot := range header.SlotsCount {
+		tuple, err := page.ReadTuple(slot)
+		if errors.Is(err, storage.ErrTupleDeleted) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(slot, tuple); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"errors"
+	"fmt"
 	"path"
 	"sync"
 )
@@ -76,6 +77,17 @@ func (p *PageDirectory) UnregisterFile(relation string) error {
 	return nil
 }
 
+func (p *PageDirectory) GetFilePath(relation string) (string, error) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	relDirectory, found := p.relationMap[relation]
+	if !found {
+		return "", ErrRelationNotExists
+	}
+	return relDirectory.file, nil
+}
+
 func (p *PageDirectory) GetPageLoc(id PageId) (PhysLoc, error) {
 	p.mutex.RLock()
 	defer p.mutex.RUnlock()
@@ -127,3 +139,46 @@ func (p *PageDirectory) UnregisterPage(id PageId) error {
 	delete(relation.pageMap, id.Id)
 	return nil
 }
+
+// ClearPages unregisters all pages of a relation while keeping the relation itself.
+func (p *PageDirectory) ClearPages(relation string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	relDirectory, found := p.relationMap[relation]
+	if !found {
+		return ErrRelationNotExists
+	}
+
+	clear(relDirectory.pageMap)
+	return nil
+}
+
+// OpenRelationFile registers a relation and the pages of its file, creating the file if it doesn't exist.
+// Pages are stored contiguously, page N being at offset N*PageSize. It returns the relation pages count.
+func OpenRelationFile(store *Manager, directory *PageDirectory, mainRel string, relation string) (uint32, error) {
+	fpath, err := directory.RegisterFile(mainRel, relation)
+	if err != nil {
+		return 0, err
+	}
+
+	err = store.CreateFile(fpath)
+	if err != nil && !errors.Is(err, ErrFileAlreadyExists) {
+		directory.UnregisterFile(relation)
+		return 0, fmt.Errorf("failed to create relation file: %w", err)
+	}
+
+	pageCount, err := store.PageCount(fpath)
+	if err != nil {
+		directory.UnregisterFile(relation)
+		return 0, fmt.Errorf("failed to read relation file size: %w", err)
+	}
+
+	for id := range pageCount {
+		if _, err := directory.RegisterPage(PageId{Id: id, Relation: relation}, id*PageSize); err != nil {
+			directory.UnregisterFile(relation)
+			return 0, err
+		}
+	}
+	return pageCount, nil
+}
@@ -16,6 +16,7 @@ var (
 	ErrIncompletePageRead  = errors.New("unexpected page read bytes count")
 	ErrIncompletePageWrite = errors.New("unexpected page write bytes count")
 	ErrFileAlreadyExists   = errors.New("file already exists")
+	ErrPartialPage         = errors.New("file size isn't a multiple of the page size")
 )
 
 type fileWrapper struct {
@@ -137,6 +138,74 @@ func (m *Manager) DeleteFile(fpath string) error {
 	return nil
 }
 
+// PageCount returns the number of pages stored in a file.
+func (m *Manager) PageCount(fpath string) (uint32, error) {
+	file, err := m.getFileHandle(fpath)
+	if err != nil {
+		return 0, err
+	}
+
+	file.mutex.RLock()
+	defer file.mutex.RUnlock()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if info.Size()%PageSize != 0 {
+		return 0, ErrPartialPage
+	}
+	return uint32(info.Size() / PageSize), nil
+}
+
+// ExtendFile appends zeroed pages to a file and returns the offset of the first one.
+func (m *Manager) ExtendFile(fpath string, count uint32) (uint32, error) {
+	file, err := m.getFileHandle(fpath)
+	if err != nil {
+		return 0, err
+	}
+
+	file.mutex.Lock()
+	defer file.mutex.Unlock()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if info.Size()%PageSize != 0 {
+		return 0, ErrPartialPage
+	}
+
+	buffer := make([]byte, int(count)*PageSize)
+	writeCount, err := file.WriteAt(buffer, info.Size())
+	if err != nil {
+		return 0, err
+	}
+	if writeCount != len(buffer) {
+		return 0, ErrIncompletePageWrite
+	}
+
+	if err := file.Sync(); err != nil {
+		return 0, err
+	}
+	return uint32(info.Size()), nil
+}
+
+func (m *Manager) TruncateFile(fpath string) error {
+	file, err := m.getFileHandle(fpath)
+	if err != nil {
+		return err
+	}
+
+	file.mutex.Lock()
+	defer file.mutex.Unlock()
+
+	if err := file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate file: %w", err)
+	}
+	return file.Sync()
+}
+
 func (m *Manager) getFileHandle(path string) (*fileWrapper, error) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
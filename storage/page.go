@@ -7,9 +7,10 @@ import (
 )
 
 const (
-	PageSize         = 4096
-	SlotsStartOffset = 9 // After page header
-	SlotSize         = 5
+	PageSize           = 4096
+	SlotsStartOffset   = 9 // After page header
+	SlotSize           = 5
+	EmptyPageFreeSpace = PageSize - SlotsStartOffset
 )
 
 const (
@@ -61,45 +62,66 @@ type Page struct {
 	Data     []byte
 }
 
+// Init sets the header of an empty page, without slots nor cells.
+func (p *Page) Init(pageType uint8) error {
+	p.Header = PageHeader{
+		PageType:       pageType,
+		FreeSpace:      EmptyPageFreeSpace,
+		SlotsEndOffset: SlotsStartOffset,
+		CellsEndOffset: PageSize,
+	}
+	return p.WritePageHeader()
+}
+
 func (p *Page) LoadPageHeader() error {
+	header, err := p.ReadPageHeader()
+	if err != nil {
+		return err
+	}
+
+	p.Header = header
+	return nil
+}
+
+// ReadPageHeader decodes the page header without storing it in the page, for readers sharing the page.
+func (p *Page) ReadPageHeader() (PageHeader, error) {
 	offset := uint16(0)
 	pageType, err := data.ReadByte(p.Data, offset)
 	if err != nil {
-		return err
+		return PageHeader{}, err
 	}
 
 	offset++
 	slotsCount, err := data.ReadUint16(p.Data, offset)
 	if err != nil {
-		return err
+		return PageHeader{}, err
 	}
 
 	offset += 2
 	freeSpace, err := data.ReadUint16(p.Data, offset)
 	if err != nil {
-		return err
+		return PageHeader{}, err
 	}
 
 	offset += 2
 	slotsEndOffset, err := data.ReadUint16(p.Data, offset)
 	if err != nil {
-		return err
+		return PageHeader{}, err
 	}
 
 	offset += 2
 	cellsEndOffset, err := data.ReadUint16(p.Data, offset)
 	if err != nil {
-		return err
+		return PageHeader{}, err
 	}
 
-	p.Header = PageHeader{
+	return PageHeader{
 		PageType:       pageType,
 		SlotsCount:     slotsCount,
 		FreeSpace:      freeSpace,
 		SlotsEndOffset: slotsEndOffset,
 		CellsEndOffset: cellsEndOffset,
-	}
-	return nil
+	}, nil
 }
 
 func (p *Page) WritePageHeader() error {
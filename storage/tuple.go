@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"errors"
+
+	"github.com/tinydb/data"
+)
+
+const (
+	CellHeaderSize = 6 // Slot index (uint16) + offset (uint16) + size (uint16)
+	MaxTupleSize   = EmptyPageFreeSpace - SlotSize - CellHeaderSize
+)
+
+var (
+	ErrTupleTooLarge   = errors.New("tuple doesn't fit in a page")
+	ErrPageFull        = errors.New("not enough free space in page")
+	ErrSlotNotFound    = errors.New("slot not found")
+	ErrTupleDeleted    = errors.New("tuple is deleted")
+	ErrTupleSizeChange = errors.New("tuple size differs from stored one")
+)
+
+// TupleSpace returns the page space used by a tuple of the given size.
+func TupleSpace(size uint16) uint16 {
+	return SlotSize + CellHeaderSize + size
+}
+
+// Tuple operations decode the header from the page data, so that readers sharing the page
+// don't need to load it. Slots grow from the header towards the page end,
+// cells grow from the page end towards the slots.
+
+// InsertTuple stores a tuple in a new slot and returns the slot index.
+// The page header is loaded and updated.
+func (p *Page) InsertTuple(tuple []byte) (uint16, error) {
+	if err := p.LoadPageHeader(); err != nil {
+		return 0, err
+	}
+
+	size := uint16(len(tuple))
+	if len(tuple) > EmptyPageFreeSpace || p.Header.FreeSpace < TupleSpace(size) {
+		return 0, ErrPageFull
+	}
+
+	slotIndex := p.Header.SlotsCount
+	cellOffset := p.Header.CellsEndOffset - CellHeaderSize - size
+	cell := Cell{
+		Id: TupleId{
+			SlotIndex: slotIndex,
+			Offset:    cellOffset,
+		},
+		Size: size,
+	}
+	if err := p.WriteCell(cell, cellOffset); err != nil {
+		return 0, err
+	}
+	if err := data.WriteBytes(tuple, p.Data, cellOffset+CellHeaderSize); err != nil {
+		return 0, err
+	}
+
+	if _, err := p.WriteSlot(Slot{CellOffset: cellOffset}, slotOffset(slotIndex)); err != nil {
+		return 0, err
+	}
+
+	p.Header.SlotsCount++
+	p.Header.FreeSpace -= TupleSpace(size)
+	p.Header.SlotsEndOffset = slotOffset(slotIndex) + SlotSize
+	p.Header.CellsEndOffset = cellOffset
+	return slotIndex, p.WritePageHeader()
+}
+
+// ReadTuple returns a copy of the tuple stored in a slot.
+func (p *Page) ReadTuple(slotIndex uint16) ([]byte, error) {
+	cell, err := p.readSlotCell(slotIndex)
+	if err != nil {
+		return nil, err
+	}
+	return data.ReadBytes(p.Data, cell.Id.Offset+CellHeaderSize, cell.Size)
+}
+
+// UpdateTuple overwrites the tuple stored in a slot with a tuple of the same size.
+func (p *Page) UpdateTuple(slotIndex uint16, tuple []byte) error {
+	cell, err := p.readSlotCell(slotIndex)
+	if err != nil {
+		return err
+	}
+	if int(cell.Size) != len(tuple) {
+		return ErrTupleSizeChange
+	}
+	return data.WriteBytes(tuple, p.Data, cell.Id.Offset+CellHeaderSize)
+}
+
+// ReplaceTuple overwrites the tuple stored in a slot with a tuple of any size, keeping the slot.
+// A tuple that isn't larger is written in place, the bytes it no longer uses aren't reclaimed.
+// A larger one is written to a new cell of the page, failing with ErrPageFull if it doesn't fit.
+// The page header is loaded and updated.
+func (p *Page) ReplaceTuple(slotIndex uint16, tuple []byte) error {
+	if err := p.LoadPageHeader(); err != nil {
+		return err
+	}
+	cell, err := p.readSlotCell(slotIndex)
+	if err != nil {
+		return err
+	}
+	size := uint16(len(tuple))
+	if len(tuple) <= int(cell.Size) {
+		cell.Size = size
+		if err := p.WriteCell(cell, cell.Id.Offset); err != nil {
+			return err
+		}
+		return data.WriteBytes(tuple, p.Data, cell.Id.Offset+CellHeaderSize)
+	}
+
+	if len(tuple) > EmptyPageFreeSpace || p.Header.FreeSpace < CellHeaderSize+size {
+		return ErrPageFull
+	}
+
+	cellOffset := p.Header.CellsEndOffset - CellHeaderSize - size
+	cell = Cell{
+		Id: TupleId{
+			SlotIndex: slotIndex,
+			Offset:    cellOffset,
+		},
+		Size: size,
+	}
+	if err := p.WriteCell(cell, cellOffset); err != nil {
+		return err
+	}
+	if err := data.WriteBytes(tuple, p.Data, cellOffset+CellHeaderSize); err != nil {
+		return err
+	}
+	if _, err := p.WriteSlot(Slot{CellOffset: cellOffset}, slotOffset(slotIndex)); err != nil {
+		return err
+	}
+
+	p.Header.FreeSpace -= CellHeaderSize + size
+	p.Header.CellsEndOffset = cellOffset
+	return p.WritePageHeader()
+}
+
+// DeleteTuple flags a slot as deleted. Its space isn't reclaimed.
+func (p *Page) DeleteTuple(slotIndex uint16) error {
+	if _, err := p.readSlotCell(slotIndex); err != nil {
+		return err
+	}
+	return p.SetSlotDeleted(slotOffset(slotIndex))
+}
+
+func (p *Page) readSlotCell(slotIndex uint16) (Cell, error) {
+	header, err := p.ReadPageHeader()
+	if err != nil {
+		return Cell{}, err
+	}
+	if slotIndex >= header.SlotsCount {
+		return Cell{}, ErrSlotNotFound
+	}
+
+	slot, err := p.ReadSlot(slotOffset(slotIndex))
+	if err != nil {
+		return Cell{}, err
+	}
+	if slot.Deleted {
+		return Cell{}, ErrTupleDeleted
+	}
+	return p.ReadCell(slot.CellOffset)
+}
+
+func slotOffset(slotIndex uint16) uint16 {
+	return SlotsStartOffset + slotIndex*SlotSize
+}